@@ -0,0 +1,84 @@
+package batch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+)
+
+// commentPrefix starts a comment that runs until the end of the line.
+const commentPrefix = "#"
+
+// ExpressionProcessor processes a single expression, such as input.Parser.
+type ExpressionProcessor interface {
+	ProcessExpression(expr string) (*calculator.Result, error)
+}
+
+// Result is the outcome of evaluating one line of a batch.
+type Result struct {
+	Line       int
+	Expression string
+	Result     *calculator.Result
+	Err        error
+}
+
+// Summary counts the outcomes of a batch.
+type Summary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d expressions, %d succeeded, %d failed", s.Total, s.Succeeded, s.Failed)
+}
+
+// Run evaluates every expression of in, one per line, and writes each result to w.
+// Blank lines and comments starting with # are skipped. Evaluation errors are
+// reported per line and counted in the summary; only read and write failures
+// are returned as errors.
+func Run(parser ExpressionProcessor, in io.Reader, w Writer) (*Summary, error) {
+	var summary Summary
+	scanner := bufio.NewScanner(in)
+	line := 0
+	for scanner.Scan() {
+		line++
+		expr := stripComment(scanner.Text())
+		if expr == "" {
+			continue
+		}
+
+		res := Result{Line: line, Expression: expr}
+		output, err := parser.ProcessExpression(expr)
+		if err != nil {
+			res.Err = err
+			summary.Failed++
+		} else {
+			res.Result = output
+			summary.Succeeded++
+		}
+		summary.Total++
+
+		if err := w.Write(res); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+func stripComment(line string) string {
+	if i := strings.Index(line, commentPrefix); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,144 @@
+package batch_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/batch"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/format"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/input"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sheet = `# regression sheet
+1 + 2
+
+2 * (3 + 4) # inline comment
+1 / 0
+`
+
+// textFormat renders results with two decimal places.
+var textFormat = format.Text{Number: format.NumberFormat{Precision: 2}}
+
+// machineFormat renders the values of jsonl and csv like a float engine.
+var machineFormat = format.Machine(false, calculator.DefaultPrecision)
+
+func setup(t *testing.T) *input.Parser {
+	t.Helper()
+	engine := calculator.NewEngine()
+	validator := input.NewValidator(engine)
+	return input.NewParser(engine, validator)
+}
+
+func TestRun(t *testing.T) {
+	tests := map[string]struct {
+		format string
+		want   string
+	}{
+		"text": {
+			format: "text",
+			want: "2: CALCULATION SUCCESS: 1 + 2 = 3.00\n" +
+				"4: CALCULATION SUCCESS: 2 * (3 + 4) = 14.00\n" +
+				"5: CALCULATION ERROR: expression 1 / 0 is invalid: cannot divide by zero\n",
+		},
+		"jsonl": {
+			format: "jsonl",
+			want: `{"line":2,"expression":"1 + 2","value":3}` + "\n" +
+				`{"line":4,"expression":"2 * (3 + 4)","value":14}` + "\n" +
+				`{"line":5,"expression":"1 / 0","error":"expression 1 / 0 is invalid: cannot divide by zero","error_kind":"division_by_zero"}` + "\n",
+		},
+		"csv": {
+			format: "csv",
+			want: "line,expression,value,error,error_kind\n" +
+				"2,1 + 2,3,,\n" +
+				"4,2 * (3 + 4),14,,\n" +
+				"5,1 / 0,,expression 1 / 0 is invalid: cannot divide by zero,division_by_zero\n",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			var out bytes.Buffer
+			w, err := batch.NewWriter(tc.format, &out, textFormat, machineFormat)
+			require.Nil(t, err)
+
+			// Act
+			summary, err := batch.Run(setup(t), strings.NewReader(sheet), w)
+
+			// Assert
+			require.Nil(t, err)
+			require.NotNil(t, summary)
+			assert.Equal(t, batch.Summary{Total: 3, Succeeded: 2, Failed: 1}, *summary)
+			assert.Equal(t, tc.want, out.String())
+		})
+	}
+}
+
+func TestRunKeepsVariablesBetweenLines(t *testing.T) {
+	// Arrange
+	var out bytes.Buffer
+	w, err := batch.NewWriter("text", &out, textFormat, machineFormat)
+	require.Nil(t, err)
+
+	// Act
+	summary, err := batch.Run(setup(t), strings.NewReader("x = 5\nx * 2\n"), w)
+
+	// Assert
+	require.Nil(t, err)
+	require.NotNil(t, summary)
+	assert.Equal(t, 0, summary.Failed)
+	assert.Contains(t, out.String(), "2: CALCULATION SUCCESS: x * 2 = 10.00")
+}
+
+func TestNewWriter(t *testing.T) {
+	t.Run("unknown format", func(t *testing.T) {
+		// Act
+		w, err := batch.NewWriter("xml", &bytes.Buffer{}, textFormat, machineFormat)
+
+		// Assert
+		assert.Nil(t, w)
+		assert.EqualError(t, err, "unknown output format xml")
+	})
+
+	t.Run("empty csv still has header", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		w, err := batch.NewWriter("csv", &out, textFormat, machineFormat)
+		require.Nil(t, err)
+
+		// Act
+		summary, err := batch.Run(setup(t), strings.NewReader("# nothing\n"), w)
+
+		// Assert
+		require.Nil(t, err)
+		assert.Equal(t, 0, summary.Total)
+		assert.Equal(t, "line,expression,value,error,error_kind\n", out.String())
+	})
+}
+
+func TestSummaryString(t *testing.T) {
+	s := batch.Summary{Total: 3, Succeeded: 2, Failed: 1}
+	assert.Equal(t, "3 expressions, 2 succeeded, 1 failed", s.String())
+}
+
+func TestRunDecimalValues(t *testing.T) {
+	// Arrange
+	engine := calculator.NewEngine(calculator.WithDecimal(4))
+	validator := input.NewValidator(engine)
+	parser := input.NewParser(engine, validator)
+	var out bytes.Buffer
+	w, err := batch.NewWriter("jsonl", &out, textFormat, format.Machine(engine.IsExact(), engine.Precision()))
+	require.Nil(t, err)
+
+	// Act
+	_, err = batch.Run(parser, strings.NewReader("0.1 + 0.2\n2 / 3\n"), w)
+
+	// Assert
+	require.Nil(t, err)
+	assert.Equal(t, `{"line":1,"expression":"0.1 + 0.2","value":0.3}`+"\n"+
+		`{"line":2,"expression":"2 / 3","value":0.6667}`+"\n", out.String())
+}
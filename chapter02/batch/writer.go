@@ -0,0 +1,132 @@
+package batch
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/format"
+)
+
+// Writer emits the results of a batch in a particular output format.
+type Writer interface {
+	Write(res Result) error
+	Flush() error
+}
+
+// NewWriter returns the writer for the named output format: text, jsonl or csv.
+// The text format renders results with formatter; the values of jsonl and csv
+// are machine readable and rendered with number, such as format.Machine.
+func NewWriter(name string, out io.Writer, formatter format.Formatter, number format.NumberFormat) (Writer, error) {
+	switch name {
+	case "text":
+		return &textWriter{out: out, formatter: formatter}, nil
+	case "jsonl":
+		return &jsonWriter{enc: json.NewEncoder(out), number: number}, nil
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(out), number: number}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %s", name)
+	}
+}
+
+// textWriter writes one human readable line per result.
+type textWriter struct {
+	out       io.Writer
+	formatter format.Formatter
+}
+
+func (tw *textWriter) Write(res Result) error {
+	var msg string
+	if res.Err != nil {
+		msg = tw.formatter.FormatError(res.Err)
+	} else {
+		msg = tw.formatter.Format(res.Result)
+	}
+	_, err := fmt.Fprintf(tw.out, "%d: %s\n", res.Line, msg)
+	return err
+}
+
+func (tw *textWriter) Flush() error {
+	return nil
+}
+
+// jsonResult is the JSON Lines representation of a Result.
+// Value is a number, or a string for results that JSON cannot represent.
+type jsonResult struct {
+	Line       int    `json:"line"`
+	Expression string `json:"expression"`
+	Value      any    `json:"value,omitempty"`
+	Error      string `json:"error,omitempty"`
+	ErrorKind  string `json:"error_kind,omitempty"`
+}
+
+// jsonWriter writes one JSON object per result.
+type jsonWriter struct {
+	enc    *json.Encoder
+	number format.NumberFormat
+}
+
+func (jw *jsonWriter) Write(res Result) error {
+	jr := jsonResult{
+		Line:       res.Line,
+		Expression: res.Expression,
+	}
+	if res.Err != nil {
+		jr.Error = res.Err.Error()
+		jr.ErrorKind = format.ErrorKind(res.Err)
+		return jw.enc.Encode(jr)
+	}
+
+	value := jw.number.Format(res.Result)
+	if math.IsInf(res.Result.Value, 0) || math.IsNaN(res.Result.Value) {
+		jr.Value = value
+	} else {
+		jr.Value = json.Number(value)
+	}
+	return jw.enc.Encode(jr)
+}
+
+func (jw *jsonWriter) Flush() error {
+	return nil
+}
+
+// csvWriter writes a header followed by one record per result.
+type csvWriter struct {
+	w             *csv.Writer
+	number        format.NumberFormat
+	headerWritten bool
+}
+
+func (cw *csvWriter) Write(res Result) error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	var value, errMsg, errKind string
+	if res.Err != nil {
+		errMsg = res.Err.Error()
+		errKind = format.ErrorKind(res.Err)
+	} else {
+		value = cw.number.Format(res.Result)
+	}
+	return cw.w.Write([]string{strconv.Itoa(res.Line), res.Expression, value, errMsg, errKind})
+}
+
+func (cw *csvWriter) Flush() error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) writeHeader() error {
+	if cw.headerWritten {
+		return nil
+	}
+	cw.headerWritten = true
+	return cw.w.Write([]string{"line", "expression", "value", "error", "error_kind"})
+}
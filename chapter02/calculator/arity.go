@@ -0,0 +1,51 @@
+package calculator
+
+import "fmt"
+
+// Arity describes how many operands an operation accepts.
+// A Max of -1 means the operation is variadic and accepts
+// any number of operands from Min upwards.
+type Arity struct {
+	Min int
+	Max int
+}
+
+// Unary is the arity of operations that take a single operand.
+var Unary = Fixed(1)
+
+// Binary is the arity of operations that take two operands.
+var Binary = Fixed(2)
+
+// Fixed returns the arity of an operation that takes exactly n operands.
+func Fixed(n int) Arity {
+	return Arity{Min: n, Max: n}
+}
+
+// AtLeast returns the arity of a variadic operation that takes n or more operands.
+func AtLeast(n int) Arity {
+	return Arity{Min: n, Max: -1}
+}
+
+// IsVariadic reports whether the arity has no upper bound.
+func (a Arity) IsVariadic() bool {
+	return a.Max < 0
+}
+
+// Accepts reports whether n operands satisfy the arity.
+func (a Arity) Accepts(n int) bool {
+	if n < a.Min {
+		return false
+	}
+	return a.IsVariadic() || n <= a.Max
+}
+
+func (a Arity) String() string {
+	switch {
+	case a.IsVariadic():
+		return fmt.Sprintf("at least %d", a.Min)
+	case a.Min == a.Max:
+		return fmt.Sprint(a.Min)
+	default:
+		return fmt.Sprintf("between %d and %d", a.Min, a.Max)
+	}
+}
@@ -0,0 +1,144 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// DefaultPrecision is the number of decimal places that results which
+// cannot be represented exactly are rounded to in decimal mode.
+const DefaultPrecision = 34
+
+// ExactFunc is the arbitrary precision implementation of an operation.
+type ExactFunc func(operands ...*big.Rat) (*big.Rat, error)
+
+// WithDecimal switches the engine to arbitrary precision decimal arithmetic
+// backed by big.Rat. Results that cannot be represented exactly, such as
+// 1 / 3 or sqrt(2), are rounded to precision decimal places.
+func WithDecimal(precision uint) Option {
+	return func(e *Engine) {
+		e.exact = true
+		e.precision = int(precision)
+	}
+}
+
+// IsExact reports whether the engine runs in decimal mode.
+func (e *Engine) IsExact() bool {
+	return e.exact
+}
+
+// Precision returns the number of decimal places used in decimal mode.
+func (e *Engine) Precision() int {
+	return e.precision
+}
+
+// EvaluateExact applies the operator of an operation to its operands
+// with arbitrary precision. Operations without an exact implementation
+// are computed in float64 and converted back.
+func (e *Engine) EvaluateExact(operation Operation) (*big.Rat, error) {
+	d, err := e.GetOperation(operation.Operator)
+	if err != nil {
+		return nil, err
+	}
+
+	operands := operation.Exact
+	if operands == nil {
+		for _, o := range operation.Operands {
+			operands = append(operands, ToRat(o))
+		}
+	}
+	if !d.Arity.Accepts(len(operands)) {
+		return nil, operandCountError(operation.Operator)
+	}
+
+	if d.Exact != nil {
+		return d.Exact(operands...)
+	}
+
+	floats := make([]float64, 0, len(operands))
+	for _, o := range operands {
+		f, _ := o.Float64()
+		floats = append(floats, f)
+	}
+	res, err := d.Func(floats...)
+	if err != nil {
+		return nil, err
+	}
+	if math.IsInf(*res, 0) || math.IsNaN(*res) {
+		return nil, fmt.Errorf("result %v cannot be represented as a decimal", *res)
+	}
+	return ToRat(*res), nil
+}
+
+// ToRat converts a float64 to the rational of its shortest decimal
+// representation, so that 0.1 becomes exactly 1/10.
+func ToRat(x float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(x, 'g', -1, 64))
+	if !ok {
+		return new(big.Rat).SetFloat64(x)
+	}
+	return r
+}
+
+func addExact(operands ...*big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Add(operands[0], operands[1]), nil
+}
+
+func subExact(operands ...*big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Sub(operands[0], operands[1]), nil
+}
+
+func multExact(operands ...*big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Mul(operands[0], operands[1]), nil
+}
+
+func divExact(operands ...*big.Rat) (*big.Rat, error) {
+	if operands[1].Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+	return new(big.Rat).Quo(operands[0], operands[1]), nil
+}
+
+func negExact(operands ...*big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Neg(operands[0]), nil
+}
+
+func absExact(operands ...*big.Rat) (*big.Rat, error) {
+	return new(big.Rat).Abs(operands[0]), nil
+}
+
+func minExact(operands ...*big.Rat) (*big.Rat, error) {
+	result := operands[0]
+	for _, o := range operands[1:] {
+		if o.Cmp(result) < 0 {
+			result = o
+		}
+	}
+	return new(big.Rat).Set(result), nil
+}
+
+func maxExact(operands ...*big.Rat) (*big.Rat, error) {
+	result := operands[0]
+	for _, o := range operands[1:] {
+		if o.Cmp(result) > 0 {
+			result = o
+		}
+	}
+	return new(big.Rat).Set(result), nil
+}
+
+// sqrtExact computes the square root with a big.Float whose mantissa
+// is wide enough for the engine's decimal precision.
+func (e *Engine) sqrtExact(operands ...*big.Rat) (*big.Rat, error) {
+	if operands[0].Sign() < 0 {
+		return nil, fmt.Errorf("cannot take square root of negative number")
+	}
+	// log2(10) bits per decimal digit plus guard bits.
+	prec := uint(float64(e.precision)*math.Log2(10)) + 64
+	f := new(big.Float).SetPrec(prec).SetRat(operands[0])
+	f.Sqrt(f)
+	r, _ := f.Rat(nil)
+	return r, nil
+}
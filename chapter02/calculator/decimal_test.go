@@ -0,0 +1,109 @@
+package calculator_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rat(t *testing.T, s string) *big.Rat {
+	t.Helper()
+	r, ok := new(big.Rat).SetString(s)
+	require.True(t, ok)
+	return r
+}
+
+func TestEvaluateExact(t *testing.T) {
+	// Arrange
+	engine := calculator.NewEngine(calculator.WithDecimal(10))
+
+	tests := map[string]struct {
+		operator string
+		operands []string
+		want     string
+		wantErr  string
+	}{
+		"addition":          {operator: "+", operands: []string{"0.1", "0.2"}, want: "3/10"},
+		"subtraction":       {operator: "-", operands: []string{"0.3", "0.1"}, want: "1/5"},
+		"multiplication":    {operator: "*", operands: []string{"1.1", "1.1"}, want: "121/100"},
+		"division":          {operator: "/", operands: []string{"1", "3"}, want: "1/3"},
+		"division by zero":  {operator: "/", operands: []string{"1", "0"}, wantErr: "cannot divide by zero"},
+		"perfect square":    {operator: "sqrt", operands: []string{"16"}, want: "4"},
+		"negative sqrt":     {operator: "sqrt", operands: []string{"-4"}, wantErr: "cannot take square root of negative number"},
+		"max":               {operator: "max", operands: []string{"0.1", "0.3", "0.2"}, want: "3/10"},
+		"float fallback":    {operator: "cos", operands: []string{"0"}, want: "1"},
+		"unknown operation": {operator: "%", operands: []string{"1", "2"}, wantErr: "no operation for operator % found"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			var operands []*big.Rat
+			for _, o := range tc.operands {
+				operands = append(operands, rat(t, o))
+			}
+
+			// Act
+			result, err := engine.EvaluateExact(calculator.Operation{Operator: tc.operator, Exact: operands})
+
+			// Assert
+			if tc.wantErr != "" {
+				require.Nil(t, result)
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.Nil(t, err)
+			require.NotNil(t, result)
+			assert.Equal(t, tc.want, result.RatString())
+		})
+	}
+}
+
+func TestProcessOperationDecimal(t *testing.T) {
+	t.Run("exact result", func(t *testing.T) {
+		// Arrange
+		engine := calculator.NewEngine(calculator.WithDecimal(calculator.DefaultPrecision))
+		operation := calculator.Operation{
+			Expression: "0.1 + 0.2",
+			Operator:   "+",
+			Operands:   []float64{0.1, 0.2},
+		}
+
+		// Act
+		result, err := engine.ProcessOperation(operation)
+
+		// Assert
+		require.Nil(t, err)
+		require.NotNil(t, result)
+		require.NotNil(t, result.Exact)
+		assert.Equal(t, "3/10", result.Exact.RatString())
+		assert.Equal(t, 0.3, result.Value)
+	})
+
+	t.Run("inexact result", func(t *testing.T) {
+		// Arrange
+		engine := calculator.NewEngine(calculator.WithDecimal(5))
+		operation := calculator.Operation{
+			Expression: "2 / 3",
+			Operator:   "/",
+			Operands:   []float64{2, 3},
+		}
+
+		// Act
+		result, err := engine.ProcessOperation(operation)
+
+		// Assert
+		require.Nil(t, err)
+		require.NotNil(t, result)
+		require.NotNil(t, result.Exact)
+		assert.Equal(t, "2/3", result.Exact.RatString())
+	})
+}
+
+func TestToRat(t *testing.T) {
+	assert.Equal(t, "1/10", calculator.ToRat(0.1).RatString())
+	assert.Equal(t, "-5/2", calculator.ToRat(-2.5).RatString())
+}
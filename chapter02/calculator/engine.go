@@ -2,8 +2,10 @@ package calculator
 
 import (
 	"fmt"
-
-	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/format"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
 )
 
 // Operation is the wrapper object that contains
@@ -12,35 +14,55 @@ type Operation struct {
 	Expression string
 	Operator   string
 	Operands   []float64
+	// Exact holds the operands as exact rationals in decimal mode.
+	// When nil, the engine derives them from Operands.
+	Exact []*big.Rat
 }
 
+// OperationFunc is the implementation of an operation over its operands.
+type OperationFunc func(operands ...float64) (*float64, error)
+
 type Engine struct {
-	expectedLength  int
-	validOperations map[string]func(x, y float64) (*float64, error)
+	mu              sync.RWMutex
+	validOperations map[string]Definition
+	exact           bool
+	precision       int
+	env             *Environment
 }
 
-func NewEngine() *Engine {
-	engine := Engine{
-		expectedLength:  2,
-		validOperations: make(map[string]func(x, y float64) (*float64, error)),
+// NewEngine creates an engine with the default operations,
+// customised by the given options in order.
+func NewEngine(opts ...Option) *Engine {
+	engine := &Engine{
+		validOperations: make(map[string]Definition),
+		precision:       DefaultPrecision,
+		env:             NewEnvironment(),
 	}
-	engine.validOperations["+"] = engine.Add
-	engine.validOperations["-"] = engine.Sub
-	engine.validOperations["*"] = engine.Mult
-	engine.validOperations["/"] = engine.Div
-	return &engine
+	WithOperations(engine.defaultOperations()...)(engine)
+	for _, opt := range opts {
+		opt(engine)
+	}
+	return engine
 }
 
-// GetNumOperands returns the expected number of operands that the engine can process.
-func (e *Engine) GetNumOperands() int {
-	return e.expectedLength
+// GetNumOperands returns the number of operands that the given operator accepts.
+func (e *Engine) GetNumOperands(operator string) (*Arity, error) {
+	d, err := e.GetOperation(operator)
+	if err != nil {
+		return nil, err
+	}
+	return &d.Arity, nil
 }
 
+// GetValidOperators returns the symbols of all registered operations in sorted order.
 func (e *Engine) GetValidOperators() []string {
-	var operators []string
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	operators := make([]string, 0, len(e.validOperations))
 	for o := range e.validOperations {
 		operators = append(operators, o)
 	}
+	sort.Strings(operators)
 
 	return operators
 }
@@ -57,7 +79,7 @@ func (e *Engine) Sub(x, y float64) (*float64, error) {
 	return &result, nil
 }
 
-// Sub is the function that processes the subtract operation
+// Mult is the function that processes the multiply operation
 func (e *Engine) Mult(x, y float64) (*float64, error) {
 	result := x * y
 	return &result, nil
@@ -66,35 +88,140 @@ func (e *Engine) Mult(x, y float64) (*float64, error) {
 // Div is the function that processes the divide operation
 func (e *Engine) Div(x, y float64) (*float64, error) {
 	if y == 0 {
-		return nil, fmt.Errorf("cannot divide by zero")
+		return nil, ErrDivisionByZero
 	}
 	result := x / y
 	return &result, nil
 }
 
+// Neg is the function that processes the negation operation
+func (e *Engine) Neg(x float64) (*float64, error) {
+	result := -x
+	return &result, nil
+}
+
+// Sqrt is the function that processes the square root operation
+func (e *Engine) Sqrt(x float64) (*float64, error) {
+	if x < 0 {
+		return nil, fmt.Errorf("cannot take square root of negative number")
+	}
+	result := math.Sqrt(x)
+	return &result, nil
+}
+
+// Abs is the function that processes the absolute value operation
+func (e *Engine) Abs(x float64) (*float64, error) {
+	result := math.Abs(x)
+	return &result, nil
+}
+
+// Log is the function that processes the natural logarithm operation
+func (e *Engine) Log(x float64) (*float64, error) {
+	if x <= 0 {
+		return nil, fmt.Errorf("cannot take logarithm of non-positive number")
+	}
+	result := math.Log(x)
+	return &result, nil
+}
+
+// Sin is the function that processes the sine operation, in radians
+func (e *Engine) Sin(x float64) (*float64, error) {
+	result := math.Sin(x)
+	return &result, nil
+}
+
+// Cos is the function that processes the cosine operation, in radians
+func (e *Engine) Cos(x float64) (*float64, error) {
+	result := math.Cos(x)
+	return &result, nil
+}
+
+// Min is the function that returns the smallest of its operands
+func (e *Engine) Min(operands ...float64) (*float64, error) {
+	if len(operands) == 0 {
+		return nil, fmt.Errorf("min requires at least one operand")
+	}
+	result := operands[0]
+	for _, o := range operands[1:] {
+		result = math.Min(result, o)
+	}
+	return &result, nil
+}
+
+// Max is the function that returns the largest of its operands
+func (e *Engine) Max(operands ...float64) (*float64, error) {
+	if len(operands) == 0 {
+		return nil, fmt.Errorf("max requires at least one operand")
+	}
+	result := operands[0]
+	for _, o := range operands[1:] {
+		result = math.Max(result, o)
+	}
+	return &result, nil
+}
+
+// unary adapts a single operand function to an OperationFunc.
+func unary(f func(x float64) (*float64, error)) OperationFunc {
+	return func(operands ...float64) (*float64, error) {
+		return f(operands[0])
+	}
+}
+
+// binary adapts a two operand function to an OperationFunc.
+func binary(f func(x, y float64) (*float64, error)) OperationFunc {
+	return func(operands ...float64) (*float64, error) {
+		return f(operands[0], operands[1])
+	}
+}
+
 // Interface to allow mocking the Engine struct
 type OperationProcessor interface {
-	ProcessOperation(operation Operation) (*string, error)
+	ProcessOperation(operation Operation) (*Result, error)
+	Evaluate(operation Operation) (*float64, error)
 }
 
-func (e *Engine) ProcessOperation(operation Operation) (*string, error) {
-	// Comma ok idiom
-	f, ok := e.validOperations[operation.Operator]
-	if !ok {
-		err := fmt.Errorf("no operation for operator %s found", operation.Operator)
-		return nil, format.Error(operation.Expression, err)
+// ProcessOperation evaluates an operation and describes its outcome.
+// Errors are wrapped in an ExpressionError carrying the operation's expression.
+func (e *Engine) ProcessOperation(operation Operation) (*Result, error) {
+	res := &Result{
+		Expression: operation.Expression,
+		Operator:   operation.Operator,
+		Operands:   operation.Operands,
 	}
 
-	operandsLength := len(operation.Operands)
-	if operandsLength != e.GetNumOperands() {
-		err := fmt.Errorf("incorrect number of operands")
-		return nil, format.Error(operation.Expression, err)
+	if e.exact {
+		exact, err := e.EvaluateExact(operation)
+		if err != nil {
+			return nil, NewExpressionError(operation.Expression, err)
+		}
+		res.Exact = exact
+		res.Value, _ = exact.Float64()
+		return res, nil
+	}
+
+	value, err := e.Evaluate(operation)
+	if err != nil {
+		return nil, NewExpressionError(operation.Expression, err)
 	}
+	res.Value = *value
+	return res, nil
+}
 
-	res, err := f(operation.Operands[0], operation.Operands[1])
+// Evaluate applies the operator of an operation to its operands
+// and returns the unformatted result.
+func (e *Engine) Evaluate(operation Operation) (*float64, error) {
+	d, err := e.GetOperation(operation.Operator)
 	if err != nil {
-		return nil, format.Error(operation.Expression, err)
+		return nil, err
+	}
+
+	if !d.Arity.Accepts(len(operation.Operands)) {
+		return nil, operandCountError(operation.Operator)
 	}
-	fres := format.Result(operation.Expression, *res)
-	return &fres, nil
+
+	return d.Func(operation.Operands...)
+}
+
+func operandCountError(operator string) error {
+	return &ValidationError{Operator: operator, Msg: "incorrect number of operands"}
 }
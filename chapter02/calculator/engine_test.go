@@ -12,11 +12,33 @@ func TestGetNumOperands(t *testing.T) {
 	// Arrange
 	engine := calculator.NewEngine()
 
-	// Act
-	numOperands := engine.GetNumOperands()
-
-	// Assert
-	assert.Equal(t, 2, numOperands)
+	tests := map[string]struct {
+		operator string
+		want     calculator.Arity
+		wantErr  string
+	}{
+		"binary":   {operator: "+", want: calculator.Binary},
+		"unary":    {operator: "sqrt", want: calculator.Unary},
+		"variadic": {operator: "max", want: calculator.AtLeast(1)},
+		"unknown":  {operator: "%", wantErr: "no operation for operator % found"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			arity, err := engine.GetNumOperands(tc.operator)
+
+			// Assert
+			if tc.wantErr != "" {
+				require.Nil(t, arity)
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.Nil(t, err)
+			require.NotNil(t, arity)
+			assert.Equal(t, tc.want, *arity)
+		})
+	}
 }
 
 func TestGetValidOperators(t *testing.T) {
@@ -27,11 +49,15 @@ func TestGetValidOperators(t *testing.T) {
 	validOperators := engine.GetValidOperators()
 
 	// Assert
-	assert.Equal(t, 4, len(validOperators))
+	assert.Equal(t, 12, len(validOperators))
 	assert.Contains(t, validOperators, "+")
 	assert.Contains(t, validOperators, "-")
 	assert.Contains(t, validOperators, "*")
 	assert.Contains(t, validOperators, "/")
+	assert.Contains(t, validOperators, "neg")
+	assert.Contains(t, validOperators, "sqrt")
+	assert.Contains(t, validOperators, "min")
+	assert.Contains(t, validOperators, "max")
 }
 
 func TestAdd(t *testing.T) {
@@ -201,7 +227,7 @@ func TestProcessOperation(t *testing.T) {
 		// Assert
 		require.Nil(t, result)
 		require.NotNil(t, error)
-		assert.EqualError(t, error, "CALCULATION ERROR: expression "+expression+" is invalid: no operation for operator "+operator+" found")
+		assert.EqualError(t, error, "expression "+expression+" is invalid: no operation for operator "+operator+" found")
 	})
 
 	t.Run("wrong number of operands", func(t *testing.T) {
@@ -221,7 +247,7 @@ func TestProcessOperation(t *testing.T) {
 		// Assert
 		require.Nil(t, result)
 		require.NotNil(t, error)
-		assert.EqualError(t, error, "CALCULATION ERROR: expression "+expression+" is invalid: incorrect number of operands")
+		assert.EqualError(t, error, "expression "+expression+" is invalid: incorrect number of operands")
 	})
 
 	t.Run("correct input", func(t *testing.T) {
@@ -234,7 +260,12 @@ func TestProcessOperation(t *testing.T) {
 			Operator:   operator,
 			Operands:   operands,
 		}
-		want := "CALCULATION SUCCESS: " + expression + " = 0.50"
+		want := &calculator.Result{
+			Expression: expression,
+			Operator:   operator,
+			Operands:   operands,
+			Value:      0.5,
+		}
 
 		// Act
 		result, error := engine.ProcessOperation(operation)
@@ -242,7 +273,7 @@ func TestProcessOperation(t *testing.T) {
 		// Assert
 		require.Nil(t, error)
 		require.NotNil(t, result)
-		assert.Equal(t, want, *result)
+		assert.Equal(t, want, result)
 	})
 
 	t.Run("division by zero", func(t *testing.T) {
@@ -255,7 +286,7 @@ func TestProcessOperation(t *testing.T) {
 			Operator:   operator,
 			Operands:   operands,
 		}
-		want := "CALCULATION ERROR: expression 2.0 / 0.0 is invalid: cannot divide by zero"
+		want := "expression 2.0 / 0.0 is invalid: cannot divide by zero"
 
 		// Act
 		result, error := engine.ProcessOperation(operation)
@@ -264,5 +295,121 @@ func TestProcessOperation(t *testing.T) {
 		require.Nil(t, result)
 		require.NotNil(t, error)
 		assert.EqualError(t, error, want)
+		assert.ErrorIs(t, error, calculator.ErrDivisionByZero)
+	})
+}
+
+func TestEvaluate(t *testing.T) {
+	// Arrange
+	engine := calculator.NewEngine()
+
+	t.Run("correct input", func(t *testing.T) {
+		// Arrange
+		operation := calculator.Operation{
+			Expression: "3 * 4",
+			Operator:   "*",
+			Operands:   []float64{3.0, 4.0},
+		}
+
+		// Act
+		result, err := engine.Evaluate(operation)
+
+		// Assert
+		require.Nil(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, 12.0, *result)
+	})
+
+	t.Run("incorrect operator", func(t *testing.T) {
+		// Arrange
+		operation := calculator.Operation{
+			Expression: "3 % 4",
+			Operator:   "%",
+			Operands:   []float64{3.0, 4.0},
+		}
+
+		// Act
+		result, err := engine.Evaluate(operation)
+
+		// Assert
+		require.Nil(t, result)
+		assert.EqualError(t, err, "no operation for operator % found")
+	})
+}
+
+func TestUnaryOperations(t *testing.T) {
+	engine := calculator.NewEngine()
+
+	tests := map[string]struct {
+		f       func(x float64) (*float64, error)
+		x       float64
+		want    float64
+		wantErr string
+	}{
+		"neg":           {f: engine.Neg, x: 2.5, want: -2.5},
+		"sqrt":          {f: engine.Sqrt, x: 16, want: 4},
+		"sqrt negative": {f: engine.Sqrt, x: -1, wantErr: "cannot take square root of negative number"},
+		"abs":           {f: engine.Abs, x: -3, want: 3},
+		"log":           {f: engine.Log, x: 1, want: 0},
+		"log zero":      {f: engine.Log, x: 0, wantErr: "cannot take logarithm of non-positive number"},
+		"sin":           {f: engine.Sin, x: 0, want: 0},
+		"cos":           {f: engine.Cos, x: 0, want: 1},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			result, err := tc.f(tc.x)
+
+			// Assert
+			if tc.wantErr != "" {
+				require.Nil(t, result)
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.Nil(t, err)
+			require.NotNil(t, result)
+			assert.InDelta(t, tc.want, *result, 1e-9)
+		})
+	}
+}
+
+func TestVariadicOperations(t *testing.T) {
+	// Arrange
+	engine := calculator.NewEngine()
+
+	t.Run("min", func(t *testing.T) {
+		// Act
+		result, err := engine.Min(3, -1, 2)
+
+		// Assert
+		require.Nil(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, -1.0, *result)
+	})
+
+	t.Run("max", func(t *testing.T) {
+		// Act
+		result, err := engine.Max(3, -1, 2)
+
+		// Assert
+		require.Nil(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, 3.0, *result)
+	})
+
+	t.Run("evaluate with too few operands", func(t *testing.T) {
+		// Arrange
+		operation := calculator.Operation{
+			Expression: "max()",
+			Operator:   "max",
+		}
+
+		// Act
+		result, err := engine.Evaluate(operation)
+
+		// Assert
+		require.Nil(t, result)
+		assert.EqualError(t, err, "incorrect number of operands")
 	})
 }
@@ -0,0 +1,190 @@
+package calculator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Value is a number produced by evaluating an expression.
+// Exact is only tracked in decimal mode.
+type Value struct {
+	Float float64
+	Exact *big.Rat
+}
+
+// NewValue creates a value from a float, keeping its shortest decimal form as the exact value.
+func NewValue(x float64) Value {
+	v := Value{Float: x}
+	if !math.IsInf(x, 0) && !math.IsNaN(x) {
+		v.Exact = ToRat(x)
+	}
+	return v
+}
+
+// NewExactValue creates a value from an exact rational.
+func NewExactValue(r *big.Rat) Value {
+	f, _ := r.Float64()
+	return Value{Float: f, Exact: r}
+}
+
+// Rat returns the exact form of the value, deriving it from the float if needed.
+func (v Value) Rat() *big.Rat {
+	if v.Exact != nil {
+		return v.Exact
+	}
+	return ToRat(v.Float)
+}
+
+func (v Value) String() string {
+	if v.Exact != nil {
+		return v.Exact.RatString()
+	}
+	return strconv.FormatFloat(v.Float, 'g', -1, 64)
+}
+
+// WithEnvironment makes the engine evaluate variables against the given environment.
+func WithEnvironment(env *Environment) Option {
+	return func(e *Engine) {
+		e.env = env
+	}
+}
+
+// Environment returns the variables the engine evaluates against.
+func (e *Engine) Environment() *Environment {
+	return e.env
+}
+
+// builtins holds the read-only constants every environment can see.
+var builtins = &Environment{
+	vars: map[string]Value{
+		"pi": NewValue(math.Pi),
+		"e":  NewValue(math.E),
+	},
+	readOnly: true,
+}
+
+// Environment is a scope of variables that expressions are evaluated against.
+// Lookups fall back to the parent scope and finally to the built-in constants.
+type Environment struct {
+	mu       sync.RWMutex
+	parent   *Environment
+	vars     map[string]Value
+	readOnly bool
+}
+
+// NewEnvironment creates a global scope that sees the built-in constants.
+func NewEnvironment() *Environment {
+	return &Environment{
+		parent: builtins,
+		vars:   make(map[string]Value),
+	}
+}
+
+// NewScope creates a child scope whose variables shadow the ones of env.
+func (env *Environment) NewScope() *Environment {
+	return &Environment{
+		parent: env,
+		vars:   make(map[string]Value),
+	}
+}
+
+// Get returns the value of a variable, searching the enclosing scopes.
+func (env *Environment) Get(name string) (*Value, error) {
+	for scope := env; scope != nil; scope = scope.parent {
+		scope.mu.RLock()
+		v, ok := scope.vars[name]
+		scope.mu.RUnlock()
+		if ok {
+			return &v, nil
+		}
+	}
+	return nil, &UnknownVariableError{Name: name}
+}
+
+// Set assigns a variable in this scope. Built-in constants cannot be reassigned.
+func (env *Environment) Set(name string, v Value) error {
+	if env.readOnly {
+		return fmt.Errorf("cannot assign to constant %s", name)
+	}
+	if _, ok := builtins.vars[name]; ok {
+		return fmt.Errorf("cannot assign to constant %s", name)
+	}
+
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.vars[name] = v
+	return nil
+}
+
+// Names returns the sorted names of the variables defined in this scope.
+func (env *Environment) Names() []string {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+	names := make([]string, 0, len(env.vars))
+	for n := range env.vars {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save writes the variables of this scope to a JSON file.
+func (env *Environment) Save(path string) error {
+	env.mu.RLock()
+	vars := make(map[string]string, len(env.vars))
+	for n, v := range env.vars {
+		vars[n] = v.String()
+	}
+	env.mu.RUnlock()
+
+	data, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Load reads variables from a JSON file written by Save into this scope.
+// A missing file is not an error, so that the first run of a session starts empty.
+func (env *Environment) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var vars map[string]string
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return fmt.Errorf("invalid environment file %s: %v", path, err)
+	}
+	for n, s := range vars {
+		v, err := parseValue(s)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %v", n, err)
+		}
+		if err := env.Set(n, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseValue(s string) (Value, error) {
+	if r, ok := new(big.Rat).SetString(s); ok {
+		return NewExactValue(r), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{Float: f}, nil
+}
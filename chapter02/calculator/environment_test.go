@@ -0,0 +1,113 @@
+package calculator_test
+
+import (
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironment(t *testing.T) {
+	t.Run("builtin constants", func(t *testing.T) {
+		// Arrange
+		env := calculator.NewEnvironment()
+
+		// Act
+		pi, err := env.Get("pi")
+
+		// Assert
+		require.Nil(t, err)
+		require.NotNil(t, pi)
+		assert.Equal(t, math.Pi, pi.Float)
+		assert.EqualError(t, env.Set("e", calculator.NewValue(2)), "cannot assign to constant e")
+	})
+
+	t.Run("unknown variable", func(t *testing.T) {
+		// Arrange
+		env := calculator.NewEnvironment()
+
+		// Act
+		v, err := env.Get("x")
+
+		// Assert
+		require.Nil(t, v)
+		assert.EqualError(t, err, "unknown variable x")
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		// Arrange
+		global := calculator.NewEnvironment()
+		require.Nil(t, global.Set("x", calculator.NewValue(1)))
+		require.Nil(t, global.Set("y", calculator.NewValue(2)))
+		local := global.NewScope()
+		require.Nil(t, local.Set("x", calculator.NewValue(10)))
+
+		// Act
+		lx, _ := local.Get("x")
+		ly, _ := local.Get("y")
+		gx, _ := global.Get("x")
+
+		// Assert
+		assert.Equal(t, 10.0, lx.Float)
+		assert.Equal(t, 2.0, ly.Float)
+		assert.Equal(t, 1.0, gx.Float)
+		assert.Equal(t, []string{"x"}, local.Names())
+		assert.Equal(t, []string{"x", "y"}, global.Names())
+	})
+
+	t.Run("save and load", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "env.json")
+		env := calculator.NewEnvironment()
+		require.Nil(t, env.Set("rate", calculator.NewValue(0.125)))
+		require.Nil(t, env.Set("third", calculator.NewExactValue(big.NewRat(1, 3))))
+		require.Nil(t, env.Set("big", calculator.Value{Float: math.Inf(1)}))
+
+		// Act
+		require.Nil(t, env.Save(path))
+		loaded := calculator.NewEnvironment()
+		err := loaded.Load(path)
+
+		// Assert
+		require.Nil(t, err)
+		rate, err := loaded.Get("rate")
+		require.Nil(t, err)
+		assert.Equal(t, 0.125, rate.Float)
+		third, err := loaded.Get("third")
+		require.Nil(t, err)
+		assert.Equal(t, "1/3", third.Rat().RatString())
+		inf, err := loaded.Get("big")
+		require.Nil(t, err)
+		assert.True(t, math.IsInf(inf.Float, 1))
+	})
+
+	t.Run("load missing file", func(t *testing.T) {
+		// Arrange
+		env := calculator.NewEnvironment()
+
+		// Act
+		err := env.Load(filepath.Join(t.TempDir(), "missing.json"))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Empty(t, env.Names())
+	})
+
+	t.Run("load invalid file", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "env.json")
+		require.Nil(t, os.WriteFile(path, []byte(`{"x": "abc"}`), 0o644))
+		env := calculator.NewEnvironment()
+
+		// Act
+		err := env.Load(path)
+
+		// Assert
+		assert.NotNil(t, err)
+	})
+}
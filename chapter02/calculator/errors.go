@@ -0,0 +1,80 @@
+package calculator
+
+import "fmt"
+
+// ExpressionError ties the error of an evaluation to the expression it occurred in.
+type ExpressionError struct {
+	Expression string
+	Err        error
+}
+
+// NewExpressionError wraps err with the expression it occurred in,
+// unless it already carries one.
+func NewExpressionError(expr string, err error) error {
+	if _, ok := err.(*ExpressionError); ok {
+		return err
+	}
+	return &ExpressionError{Expression: expr, Err: err}
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("expression %s is invalid: %v", e.Expression, e.Err)
+}
+
+func (e *ExpressionError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError reports malformed expression syntax at a position of the input.
+type ParseError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return e.Msg
+}
+
+// ValidationError reports operands or operators that an operation does not accept.
+// Err holds the underlying cause, if any.
+type ValidationError struct {
+	Operator string
+	Msg      string
+	Err      error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Msg
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// UnknownOperatorError reports an operator that has no registered operation.
+type UnknownOperatorError struct {
+	Operator string
+}
+
+func (e *UnknownOperatorError) Error() string {
+	return fmt.Sprintf("no operation for operator %s found", e.Operator)
+}
+
+// UnknownVariableError reports a variable that is not defined in the environment.
+type UnknownVariableError struct {
+	Name string
+}
+
+func (e *UnknownVariableError) Error() string {
+	return fmt.Sprintf("unknown variable %s", e.Name)
+}
+
+// DivisionByZeroError is returned when an operation divides by zero.
+type DivisionByZeroError struct{}
+
+func (DivisionByZeroError) Error() string {
+	return "cannot divide by zero"
+}
+
+// ErrDivisionByZero is the error returned by division by zero, for use with errors.Is.
+var ErrDivisionByZero error = DivisionByZeroError{}
@@ -0,0 +1,48 @@
+package calculator_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpressionError(t *testing.T) {
+	t.Run("wraps cause", func(t *testing.T) {
+		// Act
+		err := calculator.NewExpressionError("1 / 0", calculator.ErrDivisionByZero)
+
+		// Assert
+		assert.EqualError(t, err, "expression 1 / 0 is invalid: cannot divide by zero")
+		assert.ErrorIs(t, err, calculator.ErrDivisionByZero)
+		var divErr calculator.DivisionByZeroError
+		assert.True(t, errors.As(err, &divErr))
+	})
+
+	t.Run("does not wrap twice", func(t *testing.T) {
+		// Arrange
+		inner := calculator.NewExpressionError("1 / 0", calculator.ErrDivisionByZero)
+
+		// Act
+		err := calculator.NewExpressionError("x = 1 / 0", inner)
+
+		// Assert
+		var exprErr *calculator.ExpressionError
+		require.ErrorAs(t, err, &exprErr)
+		assert.Equal(t, "1 / 0", exprErr.Expression)
+	})
+}
+
+func TestValidationError(t *testing.T) {
+	// Arrange
+	cause := &calculator.UnknownOperatorError{Operator: "%"}
+	err := &calculator.ValidationError{Operator: "%", Msg: "invalid operator: %", Err: cause}
+
+	// Assert
+	assert.EqualError(t, err, "invalid operator: %")
+	var unknownErr *calculator.UnknownOperatorError
+	require.ErrorAs(t, err, &unknownErr)
+	assert.Equal(t, "%", unknownErr.Operator)
+}
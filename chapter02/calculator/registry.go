@@ -0,0 +1,139 @@
+package calculator
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Associativity describes how operators of equal precedence are grouped.
+type Associativity int
+
+const (
+	LeftAssociative Associativity = iota
+	RightAssociative
+)
+
+// Definition describes an operation that can be registered with the engine.
+// Binary definitions with a positive Precedence can be written infix
+// (2 + 3), while every definition can be called as a function by its
+// symbol (max(1, 2)). Exact is optional and used in decimal mode.
+type Definition struct {
+	Name          string
+	Symbol        string
+	Arity         Arity
+	Precedence    int
+	Associativity Associativity
+	Func          OperationFunc
+	Exact         ExactFunc
+}
+
+// IsInfix reports whether the operation can be written between its two operands.
+func (d Definition) IsInfix() bool {
+	return d.Precedence > 0 && d.Arity == Binary
+}
+
+func (d Definition) validate() error {
+	if d.Symbol == "" {
+		return fmt.Errorf("operation %q has no symbol", d.Name)
+	}
+	if d.Func == nil {
+		return fmt.Errorf("operation %s has no implementation", d.Symbol)
+	}
+	if d.Arity.Min < 0 || (!d.Arity.IsVariadic() && d.Arity.Max < d.Arity.Min) {
+		return fmt.Errorf("operation %s has invalid arity %s", d.Symbol, d.Arity)
+	}
+	return nil
+}
+
+// Option configures an Engine created by NewEngine.
+type Option func(*Engine)
+
+// WithoutDefaults removes every operation registered before it,
+// including the default arithmetic and function operations.
+func WithoutDefaults() Option {
+	return func(e *Engine) {
+		e.validOperations = make(map[string]Definition)
+	}
+}
+
+// WithOperations registers an operator pack, replacing any operation
+// with the same symbol. It panics if a definition is invalid.
+func WithOperations(defs ...Definition) Option {
+	return func(e *Engine) {
+		for _, d := range defs {
+			if err := d.validate(); err != nil {
+				panic(fmt.Sprintf("calculator: %v", err))
+			}
+			e.validOperations[d.Symbol] = d
+		}
+	}
+}
+
+// Register adds a new operation to the engine.
+func (e *Engine) Register(d Definition) error {
+	if err := d.validate(); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.validOperations[d.Symbol]; ok {
+		return fmt.Errorf("operator %s is already registered", d.Symbol)
+	}
+	e.validOperations[d.Symbol] = d
+	return nil
+}
+
+// Unregister removes the operation with the given symbol from the engine.
+func (e *Engine) Unregister(symbol string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.validOperations[symbol]; !ok {
+		return &UnknownOperatorError{Operator: symbol}
+	}
+	delete(e.validOperations, symbol)
+	return nil
+}
+
+// GetOperation returns the definition registered for the given symbol.
+func (e *Engine) GetOperation(symbol string) (*Definition, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	d, ok := e.validOperations[symbol]
+	if !ok {
+		return nil, &UnknownOperatorError{Operator: symbol}
+	}
+	return &d, nil
+}
+
+// GetOperations returns every registered definition sorted by symbol.
+func (e *Engine) GetOperations() []Definition {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	defs := make([]Definition, 0, len(e.validOperations))
+	for _, d := range e.validOperations {
+		defs = append(defs, d)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].Symbol < defs[j].Symbol
+	})
+	return defs
+}
+
+// defaultOperations returns the operations every engine starts with.
+func (e *Engine) defaultOperations() []Definition {
+	return []Definition{
+		{Name: "addition", Symbol: "+", Arity: Binary, Precedence: 1, Func: binary(e.Add), Exact: addExact},
+		{Name: "subtraction", Symbol: "-", Arity: Binary, Precedence: 1, Func: binary(e.Sub), Exact: subExact},
+		{Name: "multiplication", Symbol: "*", Arity: Binary, Precedence: 2, Func: binary(e.Mult), Exact: multExact},
+		{Name: "division", Symbol: "/", Arity: Binary, Precedence: 2, Func: binary(e.Div), Exact: divExact},
+		{Name: "negation", Symbol: "neg", Arity: Unary, Func: unary(e.Neg), Exact: negExact},
+		{Name: "square root", Symbol: "sqrt", Arity: Unary, Func: unary(e.Sqrt), Exact: e.sqrtExact},
+		{Name: "absolute value", Symbol: "abs", Arity: Unary, Func: unary(e.Abs), Exact: absExact},
+		{Name: "natural logarithm", Symbol: "log", Arity: Unary, Func: unary(e.Log)},
+		{Name: "sine", Symbol: "sin", Arity: Unary, Func: unary(e.Sin)},
+		{Name: "cosine", Symbol: "cos", Arity: Unary, Func: unary(e.Cos)},
+		{Name: "minimum", Symbol: "min", Arity: AtLeast(1), Func: e.Min, Exact: minExact},
+		{Name: "maximum", Symbol: "max", Arity: AtLeast(1), Func: e.Max, Exact: maxExact},
+	}
+}
@@ -0,0 +1,150 @@
+package calculator_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func power() calculator.Definition {
+	return calculator.Definition{
+		Name:          "power",
+		Symbol:        "^",
+		Arity:         calculator.Binary,
+		Precedence:    3,
+		Associativity: calculator.RightAssociative,
+		Func: func(operands ...float64) (*float64, error) {
+			result := math.Pow(operands[0], operands[1])
+			return &result, nil
+		},
+	}
+}
+
+func TestRegister(t *testing.T) {
+	t.Run("new operation", func(t *testing.T) {
+		// Arrange
+		engine := calculator.NewEngine()
+
+		// Act
+		err := engine.Register(power())
+
+		// Assert
+		require.Nil(t, err)
+		assert.Contains(t, engine.GetValidOperators(), "^")
+		result, err := engine.Evaluate(calculator.Operation{Operator: "^", Operands: []float64{2, 10}})
+		require.Nil(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, 1024.0, *result)
+	})
+
+	t.Run("duplicate operation", func(t *testing.T) {
+		// Arrange
+		engine := calculator.NewEngine()
+		def := power()
+		def.Symbol = "+"
+
+		// Act
+		err := engine.Register(def)
+
+		// Assert
+		assert.EqualError(t, err, "operator + is already registered")
+	})
+
+	t.Run("invalid operations", func(t *testing.T) {
+		engine := calculator.NewEngine()
+		tests := map[string]struct {
+			def     calculator.Definition
+			wantErr string
+		}{
+			"no symbol":         {def: calculator.Definition{Name: "nothing", Func: power().Func}, wantErr: `operation "nothing" has no symbol`},
+			"no implementation": {def: calculator.Definition{Symbol: "?", Arity: calculator.Unary}, wantErr: "operation ? has no implementation"},
+			"invalid arity":     {def: calculator.Definition{Symbol: "?", Arity: calculator.Arity{Min: 3, Max: 1}, Func: power().Func}, wantErr: "operation ? has invalid arity between 3 and 1"},
+		}
+
+		for name, tc := range tests {
+			t.Run(name, func(t *testing.T) {
+				// Act
+				err := engine.Register(tc.def)
+
+				// Assert
+				assert.EqualError(t, err, tc.wantErr)
+			})
+		}
+	})
+}
+
+func TestUnregister(t *testing.T) {
+	t.Run("existing operation", func(t *testing.T) {
+		// Arrange
+		engine := calculator.NewEngine()
+
+		// Act
+		err := engine.Unregister("/")
+
+		// Assert
+		require.Nil(t, err)
+		assert.NotContains(t, engine.GetValidOperators(), "/")
+		_, err = engine.Evaluate(calculator.Operation{Operator: "/", Operands: []float64{1, 2}})
+		assert.EqualError(t, err, "no operation for operator / found")
+	})
+
+	t.Run("unknown operation", func(t *testing.T) {
+		// Arrange
+		engine := calculator.NewEngine()
+
+		// Act
+		err := engine.Unregister("%")
+
+		// Assert
+		assert.EqualError(t, err, "no operation for operator % found")
+	})
+}
+
+func TestEngineOptions(t *testing.T) {
+	t.Run("operator pack", func(t *testing.T) {
+		// Act
+		engine := calculator.NewEngine(calculator.WithOperations(power()))
+
+		// Assert
+		def, err := engine.GetOperation("^")
+		require.Nil(t, err)
+		require.NotNil(t, def)
+		assert.Equal(t, "power", def.Name)
+		assert.Equal(t, calculator.RightAssociative, def.Associativity)
+		assert.Contains(t, engine.GetValidOperators(), "+")
+	})
+
+	t.Run("without defaults", func(t *testing.T) {
+		// Act
+		engine := calculator.NewEngine(calculator.WithoutDefaults(), calculator.WithOperations(power()))
+
+		// Assert
+		assert.Equal(t, []string{"^"}, engine.GetValidOperators())
+	})
+
+	t.Run("invalid pack", func(t *testing.T) {
+		assert.Panics(t, func() {
+			calculator.NewEngine(calculator.WithOperations(calculator.Definition{Symbol: "?"}))
+		})
+	})
+
+	t.Run("sorted operators", func(t *testing.T) {
+		// Arrange
+		engine := calculator.NewEngine(calculator.WithOperations(power()))
+
+		// Act
+		operators := engine.GetValidOperators()
+		defs := engine.GetOperations()
+
+		// Assert
+		assert.True(t, sort.StringsAreSorted(operators))
+		require.Len(t, defs, len(operators))
+		for i, d := range defs {
+			assert.Equal(t, operators[i], d.Symbol)
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package calculator
+
+import "math/big"
+
+// Result is the outcome of evaluating an expression.
+// Operator and Operands describe the last operation applied and are
+// empty for expressions without one, such as a single number.
+type Result struct {
+	Expression string
+	// Variable is the name the result was assigned to, if any.
+	Variable string
+	Operator string
+	Operands []float64
+	Value    float64
+	// Exact holds the result as an exact rational in decimal mode.
+	Exact *big.Rat
+}
@@ -3,5 +3,5 @@ package format
 import "fmt"
 
 func Error(expr string, err error) error {
-	return fmt.Errorf("CALCULATION ERROR: expression %s is invalid: %v", expr, err)
+	return fmt.Errorf("CALCULATION ERROR: expression %s is invalid: %w", expr, err)
 }
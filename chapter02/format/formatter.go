@@ -0,0 +1,130 @@
+package format
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+)
+
+// Formatter renders calculation results and errors for display.
+type Formatter interface {
+	Format(res *calculator.Result) string
+	FormatError(err error) string
+}
+
+// Formatters lists the names accepted by NewFormatter.
+var Formatters = []string{"text", "plain", "json"}
+
+// NewFormatter returns the named formatter: text, plain or json.
+func NewFormatter(name string, number NumberFormat) (Formatter, error) {
+	switch name {
+	case "text":
+		return Text{Number: number}, nil
+	case "plain":
+		return Plain{Number: number}, nil
+	case "json":
+		return JSON{Number: number}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %s", name)
+	}
+}
+
+// Text renders results as CALCULATION SUCCESS and CALCULATION ERROR messages.
+type Text struct {
+	Number NumberFormat
+}
+
+func (f Text) Format(res *calculator.Result) string {
+	return fmt.Sprintf("CALCULATION SUCCESS: %s = %s", res.Expression, f.Number.Format(res))
+}
+
+func (f Text) FormatError(err error) string {
+	return Failure(err)
+}
+
+// Plain renders the bare value of a result and the bare error message,
+// which suits piping results into other tools.
+type Plain struct {
+	Number NumberFormat
+}
+
+func (f Plain) Format(res *calculator.Result) string {
+	return f.Number.Format(res)
+}
+
+func (f Plain) FormatError(err error) string {
+	return err.Error()
+}
+
+// JSON renders results and errors as single line JSON objects.
+type JSON struct {
+	Number NumberFormat
+}
+
+// jsonResult is the JSON representation of a calculator.Result. Value is a
+// number, or a string for results that JSON cannot represent, Exact is the
+// fraction of decimal mode results and Formatted is Value per the NumberFormat.
+type jsonResult struct {
+	Expression string    `json:"expression"`
+	Variable   string    `json:"variable,omitempty"`
+	Operator   string    `json:"operator,omitempty"`
+	Operands   []float64 `json:"operands,omitempty"`
+	Value      any       `json:"value"`
+	Exact      string    `json:"exact,omitempty"`
+	Formatted  string    `json:"formatted"`
+}
+
+// jsonError is the JSON representation of an evaluation error.
+type jsonError struct {
+	Expression string `json:"expression,omitempty"`
+	Error      string `json:"error"`
+	ErrorKind  string `json:"error_kind"`
+	Position   *int   `json:"position,omitempty"`
+}
+
+func (f JSON) Format(res *calculator.Result) string {
+	jr := jsonResult{
+		Expression: res.Expression,
+		Variable:   res.Variable,
+		Operator:   res.Operator,
+		Operands:   res.Operands,
+		Value:      json.Number(strconv.FormatFloat(res.Value, 'g', -1, 64)),
+		Formatted:  f.Number.Format(res),
+	}
+	if math.IsInf(res.Value, 0) || math.IsNaN(res.Value) {
+		jr.Value = strconv.FormatFloat(res.Value, 'g', -1, 64)
+	}
+	if res.Exact != nil {
+		jr.Exact = res.Exact.RatString()
+	}
+	return marshal(jr)
+}
+
+func (f JSON) FormatError(err error) string {
+	je := jsonError{
+		Error:     err.Error(),
+		ErrorKind: ErrorKind(err),
+	}
+	var exprErr *calculator.ExpressionError
+	if errors.As(err, &exprErr) {
+		je.Expression = exprErr.Expression
+		je.Error = exprErr.Err.Error()
+	}
+	var parseErr *calculator.ParseError
+	if errors.As(err, &parseErr) {
+		je.Position = &parseErr.Pos
+	}
+	return marshal(je)
+}
+
+func marshal(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf(`{"error":%q}`, err.Error())
+	}
+	return string(b)
+}
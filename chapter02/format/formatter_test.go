@@ -0,0 +1,87 @@
+package format_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatters(t *testing.T) {
+	number := format.NumberFormat{Precision: 2}
+	res := &calculator.Result{Expression: "2 + 3", Operator: "+", Operands: []float64{2, 3}, Value: 5}
+	err := calculator.NewExpressionError("1 / 0", calculator.ErrDivisionByZero)
+
+	tests := map[string]struct {
+		formatter format.Formatter
+		wantRes   string
+		wantErr   string
+	}{
+		"text": {
+			formatter: format.Text{Number: number},
+			wantRes:   "CALCULATION SUCCESS: 2 + 3 = 5.00",
+			wantErr:   "CALCULATION ERROR: expression 1 / 0 is invalid: cannot divide by zero",
+		},
+		"plain": {
+			formatter: format.Plain{Number: number},
+			wantRes:   "5.00",
+			wantErr:   "expression 1 / 0 is invalid: cannot divide by zero",
+		},
+		"json": {
+			formatter: format.JSON{Number: number},
+			wantRes:   `{"expression":"2 + 3","operator":"+","operands":[2,3],"value":5,"formatted":"5.00"}`,
+			wantErr:   `{"expression":"1 / 0","error":"cannot divide by zero","error_kind":"division_by_zero"}`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.wantRes, tc.formatter.Format(res))
+			assert.Equal(t, tc.wantErr, tc.formatter.FormatError(err))
+		})
+	}
+}
+
+func TestJSONFormatter(t *testing.T) {
+	f := format.JSON{Number: format.NumberFormat{Precision: 3}}
+
+	t.Run("exact result with variable", func(t *testing.T) {
+		res := &calculator.Result{Expression: "x = 1 / 3", Variable: "x", Value: 1.0 / 3, Exact: big.NewRat(1, 3)}
+		assert.Equal(t, `{"expression":"x = 1 / 3","variable":"x","value":0.3333333333333333,"exact":"1/3","formatted":"0.333"}`, f.Format(res))
+	})
+
+	t.Run("parse error has position", func(t *testing.T) {
+		err := calculator.NewExpressionError("1 +", &calculator.ParseError{Pos: 3, Msg: "unexpected end of expression"})
+		assert.Equal(t, `{"expression":"1 +","error":"unexpected end of expression","error_kind":"parse","position":3}`, f.FormatError(err))
+	})
+
+	t.Run("error without expression", func(t *testing.T) {
+		assert.Equal(t, `{"error":"boom","error_kind":"evaluation"}`, f.FormatError(errors.New("boom")))
+	})
+}
+
+func TestNewFormatter(t *testing.T) {
+	for _, name := range format.Formatters {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			f, error := format.NewFormatter(name, format.NumberFormat{})
+
+			// Assert
+			require.Nil(t, error)
+			assert.NotNil(t, f)
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		// Act
+		f, error := format.NewFormatter("xml", format.NumberFormat{})
+
+		// Assert
+		assert.Nil(t, f)
+		assert.EqualError(t, error, "unknown format xml")
+	})
+}
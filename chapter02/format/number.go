@@ -0,0 +1,160 @@
+package format
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+)
+
+// NumberFormat controls how the value of a result is rendered.
+type NumberFormat struct {
+	// Precision is the number of decimal places. A negative precision
+	// shows the shortest representation that identifies the value.
+	Precision int
+	// Scientific renders values in scientific notation, such as 1.23e+04.
+	Scientific bool
+	// TrimZeros removes trailing zeros from the decimal places.
+	TrimZeros bool
+	// ThousandsSeparator groups the integer digits by three when set.
+	ThousandsSeparator string
+	// DecimalSeparator replaces the decimal point when set.
+	DecimalSeparator string
+}
+
+// Machine returns the NumberFormat of machine readable values, such as
+// those of JSON and CSV output: float results in their shortest form, and
+// results of an engine in decimal mode rounded to its precision.
+func Machine(exact bool, precision int) NumberFormat {
+	if exact {
+		return NumberFormat{Precision: precision, TrimZeros: true}
+	}
+	return NumberFormat{Precision: -1}
+}
+
+// locales maps language tags to their thousands and decimal separators.
+var locales = map[string][2]string{
+	"en":    {",", "."},
+	"en-US": {",", "."},
+	"en-GB": {",", "."},
+	"de":    {".", ","},
+	"de-CH": {"'", "."},
+	"es":    {".", ","},
+	"fr":    {" ", ","},
+	"it":    {".", ","},
+	"nl":    {".", ","},
+	"pt":    {".", ","},
+	"pt-BR": {".", ","},
+	"ru":    {" ", ","},
+	"mk":    {".", ","},
+	"ja":    {",", "."},
+	"zh":    {",", "."},
+}
+
+// WithLocale returns a copy of nf using the separators of the given language tag.
+func (nf NumberFormat) WithLocale(tag string) (NumberFormat, error) {
+	seps, ok := locales[tag]
+	if !ok {
+		seps, ok = locales[strings.SplitN(tag, "-", 2)[0]]
+	}
+	if !ok {
+		return nf, fmt.Errorf("unsupported locale %s", tag)
+	}
+	nf.ThousandsSeparator, nf.DecimalSeparator = seps[0], seps[1]
+	return nf, nil
+}
+
+// Format renders the value of a result.
+func (nf NumberFormat) Format(res *calculator.Result) string {
+	if res.Exact != nil {
+		return nf.FormatRat(res.Exact)
+	}
+	return nf.FormatFloat(res.Value)
+}
+
+// FormatFloat renders a float64 value.
+func (nf NumberFormat) FormatFloat(x float64) string {
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	verb := byte('f')
+	if nf.Scientific {
+		verb = 'e'
+	}
+	return nf.localize(strconv.FormatFloat(x, verb, nf.Precision, 64))
+}
+
+// FormatRat renders an exact value. A negative precision falls back to
+// calculator.DefaultPrecision with trailing zeros removed.
+func (nf NumberFormat) FormatRat(r *big.Rat) string {
+	prec := nf.Precision
+	if prec < 0 {
+		prec = calculator.DefaultPrecision
+		nf.TrimZeros = true
+	}
+
+	var s string
+	if nf.Scientific {
+		// log2(10) bits per decimal digit, plus the digits of the integer part.
+		bits := uint(float64(prec+r.Num().BitLen())*math.Log2(10)) + 64
+		s = new(big.Float).SetPrec(bits).SetRat(r).Text('e', prec)
+	} else {
+		s = r.FloatString(prec)
+	}
+	return nf.localize(s)
+}
+
+// localize trims zeros and applies the separators to a number
+// in the [-]ddd[.ddd][e±dd] form produced by strconv and math/big.
+func (nf NumberFormat) localize(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	exponent := ""
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		s, exponent = s[:i], s[i:]
+	}
+	integer, fraction, _ := strings.Cut(s, ".")
+
+	if nf.TrimZeros {
+		fraction = strings.TrimRight(fraction, "0")
+	}
+	if nf.ThousandsSeparator != "" {
+		integer = group(integer, nf.ThousandsSeparator)
+	}
+	if sign == "-" && strings.Trim(integer+fraction, "0") == "" {
+		sign = ""
+	}
+
+	point := "."
+	if nf.DecimalSeparator != "" {
+		point = nf.DecimalSeparator
+	}
+	if fraction == "" {
+		return sign + integer + exponent
+	}
+	return sign + integer + point + fraction + exponent
+}
+
+// group inserts sep between every three digits, counting from the right.
+func group(digits, sep string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package format_test
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumberFormat(t *testing.T) {
+	tests := map[string]struct {
+		number format.NumberFormat
+		res    *calculator.Result
+		want   string
+	}{
+		"fixed precision":      {number: format.NumberFormat{Precision: 2}, res: &calculator.Result{Value: 5}, want: "5.00"},
+		"shortest":             {number: format.NumberFormat{Precision: -1}, res: &calculator.Result{Value: 0.1}, want: "0.1"},
+		"scientific":           {number: format.NumberFormat{Precision: 3, Scientific: true}, res: &calculator.Result{Value: 12345}, want: "1.234e+04"},
+		"trim zeros":           {number: format.NumberFormat{Precision: 4, TrimZeros: true}, res: &calculator.Result{Value: 2.5}, want: "2.5"},
+		"trim whole number":    {number: format.NumberFormat{Precision: 4, TrimZeros: true}, res: &calculator.Result{Value: 3}, want: "3"},
+		"thousands separator":  {number: format.NumberFormat{Precision: 2, ThousandsSeparator: ","}, res: &calculator.Result{Value: -1234567.5}, want: "-1,234,567.50"},
+		"decimal separator":    {number: format.NumberFormat{Precision: 1, DecimalSeparator: ","}, res: &calculator.Result{Value: 0.5}, want: "0,5"},
+		"negative zero":        {number: format.NumberFormat{Precision: 2}, res: &calculator.Result{Value: -0.001}, want: "0.00"},
+		"infinity":             {number: format.NumberFormat{Precision: 2, ThousandsSeparator: ","}, res: &calculator.Result{Value: math.Inf(1)}, want: "+Inf"},
+		"exact":                {number: format.NumberFormat{Precision: 3}, res: &calculator.Result{Exact: big.NewRat(2, 3)}, want: "0.667"},
+		"exact shortest":       {number: format.NumberFormat{Precision: -1}, res: &calculator.Result{Exact: big.NewRat(1, 4)}, want: "0.25"},
+		"exact scientific":     {number: format.NumberFormat{Precision: 2, Scientific: true}, res: &calculator.Result{Exact: big.NewRat(123456, 1)}, want: "1.23e+05"},
+		"exact with separator": {number: format.NumberFormat{Precision: 1, ThousandsSeparator: "."}, res: &calculator.Result{Exact: big.NewRat(10000, 1)}, want: "10.000.0"},
+		"machine float":        {number: format.Machine(false, 2), res: &calculator.Result{Value: 1.0 / 3}, want: "0.3333333333333333"},
+		"machine exact":        {number: format.Machine(true, 2), res: &calculator.Result{Exact: big.NewRat(2, 3)}, want: "0.67"},
+		"machine exact whole":  {number: format.Machine(true, 2), res: &calculator.Result{Exact: big.NewRat(-3, 1)}, want: "-3"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.number.Format(tc.res))
+		})
+	}
+}
+
+func TestWithLocale(t *testing.T) {
+	res := &calculator.Result{Value: 1234.5}
+
+	t.Run("known locale", func(t *testing.T) {
+		// Act
+		number, error := format.NumberFormat{Precision: 2}.WithLocale("de")
+
+		// Assert
+		require.Nil(t, error)
+		assert.Equal(t, "1.234,50", number.Format(res))
+	})
+
+	t.Run("region falls back to language", func(t *testing.T) {
+		// Act
+		number, error := format.NumberFormat{Precision: 2}.WithLocale("it-IT")
+
+		// Assert
+		require.Nil(t, error)
+		assert.Equal(t, "1.234,50", number.Format(res))
+	})
+
+	t.Run("unknown locale", func(t *testing.T) {
+		// Act
+		_, error := format.NumberFormat{}.WithLocale("xx")
+
+		// Assert
+		assert.EqualError(t, error, "unsupported locale xx")
+	})
+}
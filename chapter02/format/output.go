@@ -0,0 +1,41 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+)
+
+// Failure renders an evaluation error for display.
+func Failure(err error) string {
+	var exprErr *calculator.ExpressionError
+	if errors.As(err, &exprErr) {
+		return Error(exprErr.Expression, exprErr.Err).Error()
+	}
+	return fmt.Sprintf("CALCULATION ERROR: %v", err)
+}
+
+// ErrorKind classifies an evaluation error with a short machine readable name.
+func ErrorKind(err error) string {
+	var (
+		unknownOperator *calculator.UnknownOperatorError
+		unknownVariable *calculator.UnknownVariableError
+		parseErr        *calculator.ParseError
+		validationErr   *calculator.ValidationError
+	)
+	switch {
+	case errors.As(err, &unknownOperator):
+		return "unknown_operator"
+	case errors.As(err, &unknownVariable):
+		return "unknown_variable"
+	case errors.Is(err, calculator.ErrDivisionByZero):
+		return "division_by_zero"
+	case errors.As(err, &parseErr):
+		return "parse"
+	case errors.As(err, &validationErr):
+		return "validation"
+	default:
+		return "evaluation"
+	}
+}
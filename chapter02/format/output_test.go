@@ -0,0 +1,41 @@
+package format_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/format"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailure(t *testing.T) {
+	t.Run("expression error", func(t *testing.T) {
+		err := calculator.NewExpressionError("1 / 0", calculator.ErrDivisionByZero)
+		assert.Equal(t, "CALCULATION ERROR: expression 1 / 0 is invalid: cannot divide by zero", format.Failure(err))
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		assert.Equal(t, "CALCULATION ERROR: boom", format.Failure(errors.New("boom")))
+	})
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want string
+	}{
+		"parse":            {err: &calculator.ParseError{Pos: 1, Msg: "bad"}, want: "parse"},
+		"validation":       {err: &calculator.ValidationError{Operator: "+", Msg: "bad"}, want: "validation"},
+		"unknown operator": {err: &calculator.ValidationError{Msg: "bad", Err: &calculator.UnknownOperatorError{Operator: "%"}}, want: "unknown_operator"},
+		"unknown variable": {err: &calculator.UnknownVariableError{Name: "x"}, want: "unknown_variable"},
+		"division by zero": {err: calculator.NewExpressionError("1 / 0", calculator.ErrDivisionByZero), want: "division_by_zero"},
+		"other":            {err: errors.New("boom"), want: "evaluation"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, format.ErrorKind(tc.err))
+		})
+	}
+}
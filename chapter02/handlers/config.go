@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// ConfigureServer configures the routes of this server and binds handler functions to them
+func ConfigureServer(handler *Handler) *mux.Router {
+	router := mux.NewRouter().StrictSlash(true)
+
+	router.Methods("POST").Path("/evaluate").Handler(http.HandlerFunc(handler.Evaluate))
+	router.Methods("POST").Path("/evaluate/batch").Handler(http.HandlerFunc(handler.EvaluateBatch))
+
+	return router
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/batch"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/format"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/input"
+)
+
+// MaxBatchSize is the largest number of expressions accepted by one batch request.
+const MaxBatchSize = 1000
+
+// maxBodySize limits the size of request bodies to 1 MiB.
+const maxBodySize = 1 << 20
+
+var errTrailingData = errors.New("unexpected data after the JSON body")
+
+// EvaluateRequest is the body of POST /evaluate.
+type EvaluateRequest struct {
+	Expression string `json:"expression"`
+}
+
+// BatchRequest is the body of POST /evaluate/batch.
+type BatchRequest struct {
+	Expressions []string `json:"expressions"`
+}
+
+// Handler evaluates expressions received over HTTP. Every request gets
+// its own engine, so variables assigned by one request are not visible
+// to others; the expressions of a batch share one engine.
+type Handler struct {
+	opts []calculator.Option
+}
+
+// NewHandler creates a Handler whose engines are configured with opts.
+func NewHandler(opts ...calculator.Option) *Handler {
+	return &Handler{
+		opts: opts,
+	}
+}
+
+// Evaluate is invoked by HTTP POST /evaluate.
+func (h *Handler) Evaluate(w http.ResponseWriter, r *http.Request) {
+	var req EvaluateRequest
+	if err := readRequest(w, r, &req); err != nil {
+		writeResponse(w, http.StatusBadRequest, &Response{
+			Error: requestError("invalid evaluate body: %v", err),
+		})
+		return
+	}
+	if strings.TrimSpace(req.Expression) == "" {
+		writeResponse(w, http.StatusBadRequest, &Response{
+			Error: requestError("expression is required"),
+		})
+		return
+	}
+
+	parser, engine := h.newParser()
+	res, err := parser.ProcessExpression(req.Expression)
+	if err != nil {
+		writeResponse(w, http.StatusUnprocessableEntity, &Response{
+			Result: &Result{Expression: req.Expression},
+			Error:  newError(err),
+		})
+		return
+	}
+
+	result := newResult(res, format.Machine(engine.IsExact(), engine.Precision()))
+	writeResponse(w, http.StatusOK, &Response{
+		Result: &result,
+	})
+}
+
+// EvaluateBatch is invoked by HTTP POST /evaluate/batch. Expressions that
+// fail are reported in their result and do not fail the request.
+func (h *Handler) EvaluateBatch(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := readRequest(w, r, &req); err != nil {
+		writeResponse(w, http.StatusBadRequest, &Response{
+			Error: requestError("invalid batch body: %v", err),
+		})
+		return
+	}
+	if len(req.Expressions) == 0 {
+		writeResponse(w, http.StatusBadRequest, &Response{
+			Error: requestError("expressions are required"),
+		})
+		return
+	}
+	if len(req.Expressions) > MaxBatchSize {
+		writeResponse(w, http.StatusRequestEntityTooLarge, &Response{
+			Error: requestError("too many expressions: got %d, want at most %d", len(req.Expressions), MaxBatchSize),
+		})
+		return
+	}
+
+	parser, engine := h.newParser()
+	results := make([]Result, 0, len(req.Expressions))
+	var summary batch.Summary
+	for _, expr := range req.Expressions {
+		summary.Total++
+		res, err := parser.ProcessExpression(expr)
+		if err != nil {
+			summary.Failed++
+			results = append(results, Result{Expression: expr, Error: newError(err)})
+			continue
+		}
+		summary.Succeeded++
+		results = append(results, newResult(res, format.Machine(engine.IsExact(), engine.Precision())))
+	}
+
+	writeResponse(w, http.StatusOK, &Response{
+		Results: results,
+		Summary: &summary,
+	})
+}
+
+func (h *Handler) newParser() (*input.Parser, *calculator.Engine) {
+	engine := calculator.NewEngine(h.opts...)
+	validator := input.NewValidator(engine)
+	return input.NewParser(engine, validator), engine
+}
+
+// readRequest decodes the JSON body of r into v, rejecting unknown fields
+// and bodies larger than maxBodySize.
+func readRequest(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errTrailingData
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/handlers"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(t *testing.T, h *handlers.Handler, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	handlers.ConfigureServer(h).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := map[string]struct {
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		"success": {
+			body:       `{"expression":"2 + 3 * 4"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"result":{"expression":"2 + 3 * 4","operator":"+","operands":[2,12],"value":14}}`,
+		},
+		"assignment": {
+			body:       `{"expression":"x = sqrt(16)"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"result":{"expression":"x = sqrt(16)","variable":"x","operator":"sqrt","operands":[16],"value":4}}`,
+		},
+		"division by zero": {
+			body:       `{"expression":"1 / 0"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody:   `{"result":{"expression":"1 / 0"},"error":{"message":"cannot divide by zero","kind":"division_by_zero"}}`,
+		},
+		"parse error": {
+			body:       `{"expression":"1 +"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody:   `{"result":{"expression":"1 +"},"error":{"message":"unexpected end of expression","kind":"parse","position":3}}`,
+		},
+		"unknown variable": {
+			body:       `{"expression":"y * 2"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody:   `{"result":{"expression":"y * 2"},"error":{"message":"unknown variable y","kind":"unknown_variable"}}`,
+		},
+		"missing expression": {
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":{"message":"expression is required","kind":"request"}}`,
+		},
+		"unknown field": {
+			body:       `{"expr":"1 + 1"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":{"message":"invalid evaluate body: json: unknown field \"expr\"","kind":"request"}}`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			rr := serve(t, handlers.NewHandler(), "/evaluate", tc.body)
+
+			// Assert
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, "application/json; charset=UTF-8", rr.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.wantBody, rr.Body.String())
+		})
+	}
+}
+
+func TestEvaluateIsolatesRequests(t *testing.T) {
+	// Arrange
+	h := handlers.NewHandler()
+	serve(t, h, "/evaluate", `{"expression":"x = 1"}`)
+
+	// Act
+	rr := serve(t, h, "/evaluate", `{"expression":"x + 1"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+}
+
+func TestEvaluateDecimal(t *testing.T) {
+	// Act
+	rr := serve(t, handlers.NewHandler(calculator.WithDecimal(4)), "/evaluate", `{"expression":"0.1 + 0.2"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"result":{"expression":"0.1 + 0.2","operator":"+","operands":[0.1,0.2],"value":0.3,"exact":"3/10"}}`, rr.Body.String())
+}
+
+func TestEvaluateBatch(t *testing.T) {
+	tests := map[string]struct {
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		"mixed results": {
+			body:       `{"expressions":["x = 5","x * 2","1 / 0"]}`,
+			wantStatus: http.StatusOK,
+			wantBody: `{"results":[` +
+				`{"expression":"x = 5","variable":"x","value":5},` +
+				`{"expression":"x * 2","operator":"*","operands":[5,2],"value":10},` +
+				`{"expression":"1 / 0","error":{"message":"cannot divide by zero","kind":"division_by_zero"}}` +
+				`],"summary":{"total":3,"succeeded":2,"failed":1}}`,
+		},
+		"no expressions": {
+			body:       `{"expressions":[]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":{"message":"expressions are required","kind":"request"}}`,
+		},
+		"malformed body": {
+			body:       `{"expressions":`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":{"message":"invalid batch body: unexpected EOF","kind":"request"}}`,
+		},
+		"too many expressions": {
+			body:       `{"expressions":[` + strings.Repeat(`"1",`, handlers.MaxBatchSize) + `"1"]}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantBody:   `{"error":{"message":"too many expressions: got 1001, want at most 1000","kind":"request"}}`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			rr := serve(t, handlers.NewHandler(), "/evaluate/batch", tc.body)
+
+			// Assert
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			assert.JSONEq(t, tc.wantBody, rr.Body.String())
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodGet, "/evaluate", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	handlers.ConfigureServer(handlers.NewHandler()).ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/batch"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/format"
+)
+
+type Response struct {
+	Result  *Result        `json:"result,omitempty"`
+	Results []Result       `json:"results,omitempty"`
+	Summary *batch.Summary `json:"summary,omitempty"`
+	Error   *Error         `json:"error,omitempty"`
+}
+
+// Result is the outcome of evaluating one expression. Value is a number,
+// or a string for results that JSON cannot represent, and Exact is the
+// fraction of decimal mode results. Failed evaluations only carry an Error.
+type Result struct {
+	Expression string    `json:"expression"`
+	Variable   string    `json:"variable,omitempty"`
+	Operator   string    `json:"operator,omitempty"`
+	Operands   []float64 `json:"operands,omitempty"`
+	Value      any       `json:"value,omitempty"`
+	Exact      string    `json:"exact,omitempty"`
+	Error      *Error    `json:"error,omitempty"`
+}
+
+// Error describes why a request or an expression failed. Kind is one of
+// the names returned by format.ErrorKind, or request for invalid requests.
+type Error struct {
+	Message  string `json:"message"`
+	Kind     string `json:"kind"`
+	Position *int   `json:"position,omitempty"`
+}
+
+func newResult(res *calculator.Result, number format.NumberFormat) Result {
+	r := Result{
+		Expression: res.Expression,
+		Variable:   res.Variable,
+		Operator:   res.Operator,
+		Operands:   res.Operands,
+	}
+	value := number.Format(res)
+	if math.IsInf(res.Value, 0) || math.IsNaN(res.Value) {
+		r.Value = value
+	} else {
+		r.Value = json.Number(value)
+	}
+	if res.Exact != nil {
+		r.Exact = res.Exact.RatString()
+	}
+	return r
+}
+
+// newError describes an evaluation error. The message leaves out the
+// expression, which is part of the Result already.
+func newError(err error) *Error {
+	e := &Error{
+		Message: err.Error(),
+		Kind:    format.ErrorKind(err),
+	}
+	var exprErr *calculator.ExpressionError
+	if errors.As(err, &exprErr) {
+		e.Message = exprErr.Err.Error()
+	}
+	var parseErr *calculator.ParseError
+	if errors.As(err, &parseErr) {
+		e.Position = &parseErr.Pos
+	}
+	return e
+}
+
+// requestError describes a request that could not be evaluated at all.
+func requestError(msg string, args ...any) *Error {
+	return &Error{
+		Message: fmt.Sprintf(msg, args...),
+		Kind:    "request",
+	}
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp *Response) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+	}
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		fmt.Fprintf(w, "error encoding resp %v:%s", resp, err)
+	}
+}
@@ -0,0 +1,298 @@
+package input
+
+import (
+	"math/big"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+)
+
+// operatorInfo holds the grammar properties of an infix operator.
+type operatorInfo struct {
+	precedence    int
+	associativity calculator.Associativity
+}
+
+// defaultOperator is used for operator symbols the grammar does not know about,
+// so that the validator gets the chance to reject them.
+var defaultOperator = operatorInfo{precedence: 1, associativity: calculator.LeftAssociative}
+
+// OperatorLookup is implemented by operation processors that describe
+// the grammar of their operators, such as calculator.Engine.
+type OperatorLookup interface {
+	GetOperations() []calculator.Definition
+}
+
+// grammar holds the infix operators and the operator symbols known to the parser.
+type grammar struct {
+	infix   map[string]operatorInfo
+	symbols []string
+}
+
+// defaultGrammar is used when the operation processor does not describe its operators.
+var defaultGrammar = grammar{
+	infix: map[string]operatorInfo{
+		"+": {precedence: 1, associativity: calculator.LeftAssociative},
+		"-": {precedence: 1, associativity: calculator.LeftAssociative},
+		"*": {precedence: 2, associativity: calculator.LeftAssociative},
+		"/": {precedence: 2, associativity: calculator.LeftAssociative},
+	},
+}
+
+// newGrammar builds the grammar from a set of operation definitions.
+func newGrammar(defs []calculator.Definition) grammar {
+	g := grammar{infix: make(map[string]operatorInfo)}
+	for _, d := range defs {
+		g.symbols = append(g.symbols, d.Symbol)
+		if d.IsInfix() {
+			g.infix[d.Symbol] = operatorInfo{precedence: d.Precedence, associativity: d.Associativity}
+		}
+	}
+	return g
+}
+
+// lookupInfix returns the grammar properties of the infix operator t, if t is one.
+func (g grammar) lookupInfix(t token) (operatorInfo, bool) {
+	info, ok := g.infix[t.text]
+	switch t.kind {
+	case operatorToken:
+		if !ok {
+			return defaultOperator, true
+		}
+		return info, true
+	case identifierToken:
+		return info, ok
+	default:
+		return operatorInfo{}, false
+	}
+}
+
+// node is an element of the expression tree.
+type node interface {
+	position() int
+}
+
+// numberNode is a numeric literal, kept both as a float and as an exact rational.
+type numberNode struct {
+	value float64
+	exact *big.Rat
+	pos   int
+}
+
+// newNumberNode creates the literal for a number token, negated if requested.
+func newNumberNode(t token, negate bool, pos int) *numberNode {
+	exact, ok := new(big.Rat).SetString(t.text)
+	if !ok {
+		exact = calculator.ToRat(t.value)
+	}
+	n := &numberNode{value: t.value, exact: exact, pos: pos}
+	if negate {
+		n.value = -n.value
+		n.exact.Neg(n.exact)
+	}
+	return n
+}
+
+func (n *numberNode) position() int { return n.pos }
+
+// variableNode is a reference to a variable or constant of the environment.
+type variableNode struct {
+	name string
+	pos  int
+}
+
+func (n *variableNode) position() int { return n.pos }
+
+// assignmentNode stores the value of an expression in a variable.
+type assignmentNode struct {
+	name  string
+	value node
+	pos   int
+}
+
+func (n *assignmentNode) position() int { return n.pos }
+
+// negationOperator is the engine operator that unary minus is evaluated with.
+const negationOperator = "neg"
+
+// operationNode is an operator or function applied to its operand sub-expressions.
+type operationNode struct {
+	operator string
+	operands []node
+	pos      int
+}
+
+func (n *operationNode) position() int { return n.pos }
+
+// expressionParser is a precedence climbing parser over a token stream.
+type expressionParser struct {
+	grammar grammar
+	tokens  []token
+	current int
+}
+
+// parse builds the expression tree for the given expression,
+// reading its numbers exactly in decimal mode.
+func parse(expr string, g grammar, exact bool) (node, error) {
+	tokens, err := tokenize(expr, g.symbols, exact)
+	if err != nil {
+		return nil, err
+	}
+
+	ep := &expressionParser{grammar: g, tokens: tokens}
+	if ep.peek().kind == endToken {
+		return nil, parseErrorf(0, "empty expression")
+	}
+	if ep.isAssignment() {
+		return ep.parseAssignment()
+	}
+	tree, err := ep.parseExpression(1)
+	if err != nil {
+		return nil, err
+	}
+	if t := ep.peek(); t.kind != endToken {
+		return nil, parseErrorf(t.pos, "unexpected %q at position %d", t.text, t.pos)
+	}
+
+	return tree, nil
+}
+
+// isAssignment reports whether the tokens start with "identifier =".
+func (ep *expressionParser) isAssignment() bool {
+	return len(ep.tokens) > 2 &&
+		ep.tokens[0].kind == identifierToken &&
+		ep.tokens[1].kind == operatorToken && ep.tokens[1].text == "="
+}
+
+// parseAssignment parses "name = expression".
+func (ep *expressionParser) parseAssignment() (node, error) {
+	name := ep.next()
+	ep.next()
+	value, err := ep.parseExpression(1)
+	if err != nil {
+		return nil, err
+	}
+	if t := ep.peek(); t.kind != endToken {
+		return nil, parseErrorf(t.pos, "unexpected %q at position %d", t.text, t.pos)
+	}
+	return &assignmentNode{name: name.text, value: value, pos: name.pos}, nil
+}
+
+func (ep *expressionParser) peek() token {
+	return ep.tokens[ep.current]
+}
+
+func (ep *expressionParser) next() token {
+	t := ep.tokens[ep.current]
+	if t.kind != endToken {
+		ep.current++
+	}
+	return t
+}
+
+// parseExpression parses a sequence of operands joined by operators
+// whose precedence is at least minPrecedence.
+func (ep *expressionParser) parseExpression(minPrecedence int) (node, error) {
+	left, err := ep.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := ep.peek()
+		info, ok := ep.grammar.lookupInfix(t)
+		if !ok || info.precedence < minPrecedence {
+			return left, nil
+		}
+		ep.next()
+
+		nextMin := info.precedence + 1
+		if info.associativity == calculator.RightAssociative {
+			nextMin = info.precedence
+		}
+		right, err := ep.parseExpression(nextMin)
+		if err != nil {
+			return nil, err
+		}
+		left = &operationNode{operator: t.text, operands: []node{left, right}, pos: t.pos}
+	}
+}
+
+// parseUnary parses an optionally signed primary expression.
+// A sign directly in front of a number is folded into the literal.
+func (ep *expressionParser) parseUnary() (node, error) {
+	t := ep.peek()
+	if t.kind != operatorToken || (t.text != "-" && t.text != "+") {
+		return ep.parsePrimary()
+	}
+	ep.next()
+
+	if n := ep.peek(); n.kind == numberToken {
+		ep.next()
+		return newNumberNode(n, t.text == "-", t.pos), nil
+	}
+
+	operand, err := ep.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if t.text == "+" {
+		return operand, nil
+	}
+	return &operationNode{operator: negationOperator, operands: []node{operand}, pos: t.pos}, nil
+}
+
+// parsePrimary parses a number, a variable, a function call or a parenthesised expression.
+func (ep *expressionParser) parsePrimary() (node, error) {
+	t := ep.next()
+	switch t.kind {
+	case numberToken:
+		return newNumberNode(t, false, t.pos), nil
+	case identifierToken:
+		if ep.peek().kind != leftParenToken {
+			return &variableNode{name: t.text, pos: t.pos}, nil
+		}
+		return ep.parseCall(t)
+	case operatorToken:
+		return nil, parseErrorf(t.pos, "unexpected operator %q at position %d", t.text, t.pos)
+	case leftParenToken:
+		inner, err := ep.parseExpression(1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := ep.next(); closing.kind != rightParenToken {
+			return nil, parseErrorf(closing.pos, "missing closing parenthesis for position %d", t.pos)
+		}
+		return inner, nil
+	case rightParenToken, commaToken:
+		return nil, parseErrorf(t.pos, "unexpected %q at position %d", t.text, t.pos)
+	default:
+		return nil, parseErrorf(t.pos, "unexpected end of expression")
+	}
+}
+
+// parseCall parses the parenthesised, comma separated arguments of a function call.
+func (ep *expressionParser) parseCall(name token) (node, error) {
+	open := ep.next()
+	call := &operationNode{operator: name.text, pos: name.pos}
+	if ep.peek().kind == rightParenToken {
+		ep.next()
+		return call, nil
+	}
+
+	for {
+		arg, err := ep.parseExpression(1)
+		if err != nil {
+			return nil, err
+		}
+		call.operands = append(call.operands, arg)
+
+		switch t := ep.next(); t.kind {
+		case commaToken:
+			continue
+		case rightParenToken:
+			return call, nil
+		default:
+			return nil, parseErrorf(t.pos, "missing closing parenthesis for position %d", open.pos)
+		}
+	}
+}
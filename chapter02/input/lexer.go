@@ -0,0 +1,141 @@
+package input
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"unicode"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+)
+
+// tokenKind identifies the category of a lexical token.
+type tokenKind int
+
+const (
+	numberToken tokenKind = iota
+	operatorToken
+	identifierToken
+	commaToken
+	leftParenToken
+	rightParenToken
+	endToken
+)
+
+// token is a single lexical unit of an expression together with
+// its position in the original input.
+type token struct {
+	kind  tokenKind
+	text  string
+	value float64
+	pos   int
+}
+
+// tokenize splits an expression into numbers, operators, identifiers,
+// commas and parentheses. Operators are matched against the given
+// symbols first, longest match wins, and fall back to a single character.
+// In decimal mode, numbers are read as exact rationals.
+func tokenize(expr string, symbols []string, exact bool) ([]token, error) {
+	var tokens []token
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case isDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (isDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			// Allow an exponent such as 1e10 or 2.5E-3.
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				j := i + 1
+				if j < len(runes) && (runes[j] == '+' || runes[j] == '-') {
+					j++
+				}
+				if j < len(runes) && isDigit(runes[j]) {
+					i = j
+					for i < len(runes) && isDigit(runes[i]) {
+						i++
+					}
+				}
+			}
+			text := string(runes[start:i])
+			value, err := parseNumber(text, exact)
+			if err != nil {
+				return nil, parseErrorf(start, "invalid number %q at position %d", text, start)
+			}
+			tokens = append(tokens, token{kind: numberToken, text: text, value: value, pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || isDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: identifierToken, text: string(runes[start:i]), pos: start})
+		case r == ',':
+			tokens = append(tokens, token{kind: commaToken, text: ",", pos: i})
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: leftParenToken, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: rightParenToken, text: ")", pos: i})
+			i++
+		case isOperatorRune(r):
+			text := matchSymbol(runes[i:], symbols)
+			tokens = append(tokens, token{kind: operatorToken, text: text, pos: i})
+			i += len([]rune(text))
+		default:
+			return nil, parseErrorf(i, "unexpected character %q at position %d", r, i)
+		}
+	}
+
+	tokens = append(tokens, token{kind: endToken, pos: len(runes)})
+	return tokens, nil
+}
+
+// parseNumber returns the float value of a number literal. In decimal mode
+// the literal only has to be a valid rational: numbers beyond the range of
+// float64, such as 1e400, are kept exactly and their float value is ±Inf.
+func parseNumber(text string, exact bool) (float64, error) {
+	if !exact {
+		return strconv.ParseFloat(text, 64)
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return 0, fmt.Errorf("invalid number %q", text)
+	}
+	value, _ := r.Float64()
+	return value, nil
+}
+
+// parseErrorf creates a ParseError for the given position.
+func parseErrorf(pos int, format string, args ...any) error {
+	return &calculator.ParseError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isOperatorRune(r rune) bool {
+	return r != '(' && r != ')' && r != ',' && (unicode.IsPunct(r) || unicode.IsSymbol(r))
+}
+
+// matchSymbol returns the longest operator symbol the input starts with.
+func matchSymbol(runes []rune, symbols []string) string {
+	match := string(runes[0])
+	for _, s := range symbols {
+		sr := []rune(s)
+		if len(sr) <= len([]rune(match)) || len(sr) > len(runes) {
+			continue
+		}
+		if !isOperatorRune(sr[0]) || string(runes[:len(sr)]) != s {
+			continue
+		}
+		match = s
+	}
+	return match
+}
@@ -2,15 +2,11 @@ package input
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
+	"math/big"
 
 	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
-	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/format"
 )
 
-const expressionLength = 3
-
 type Parser struct {
 	engine    calculator.OperationProcessor
 	validator ValidationHelper
@@ -24,40 +20,168 @@ func NewParser(op calculator.OperationProcessor, v ValidationHelper) *Parser {
 	}
 }
 
-// ProcessExpression parses an expression and sends it to the calculator
-func (p *Parser) ProcessExpression(expr string) (*string, error) {
-	operation, err := p.getOperation(expr)
-	if err != nil {
-		return nil, format.Error(expr, err)
-	}
-	return p.engine.ProcessOperation(*operation)
+// ExactEvaluator is implemented by operation processors that can evaluate
+// with arbitrary precision, such as calculator.Engine in decimal mode.
+type ExactEvaluator interface {
+	IsExact() bool
+	EvaluateExact(operation calculator.Operation) (*big.Rat, error)
 }
 
-func (p *Parser) getOperation(expr string) (*calculator.Operation, error) {
-	ops := strings.Fields(expr)
+// EnvironmentProvider is implemented by operation processors that
+// evaluate variables against an environment, such as calculator.Engine.
+type EnvironmentProvider interface {
+	Environment() *calculator.Environment
+}
 
-	if len(ops) != expressionLength {
-		return nil, fmt.Errorf("incorrect expression length:got %d, want %d", len(ops), expressionLength)
+// ProcessExpression parses an expression, sends it to the calculator and
+// returns the outcome. Assignments also store the result in the environment.
+func (p *Parser) ProcessExpression(expr string) (*calculator.Result, error) {
+	tree, err := parse(expr, p.grammar(), p.exactEvaluator() != nil)
+	if err != nil {
+		return nil, calculator.NewExpressionError(expr, err)
+	}
+
+	var variable string
+	if a, ok := tree.(*assignmentNode); ok {
+		variable, tree = a.name, a.value
 	}
 
-	leftOp, err := strconv.ParseFloat(ops[0], 64)
+	res, err := p.process(expr, tree)
 	if err != nil {
-		return nil, fmt.Errorf("unable to process expression")
+		return nil, calculator.NewExpressionError(expr, err)
+	}
+
+	if variable != "" {
+		if err := p.assign(variable, res); err != nil {
+			return nil, calculator.NewExpressionError(expr, err)
+		}
 	}
-	rightOp, err := strconv.ParseFloat(ops[2], 64)
+	return res, nil
+}
+
+// process evaluates the root of an expression tree, sending
+// a root operation to the calculator as a whole.
+func (p *Parser) process(expr string, tree node) (*calculator.Result, error) {
+	root, ok := tree.(*operationNode)
+	if !ok {
+		v, err := p.evaluate(expr, tree)
+		if err != nil {
+			return nil, err
+		}
+		res := &calculator.Result{Expression: expr, Value: v.Float}
+		if p.exactEvaluator() != nil {
+			res.Exact = v.Rat()
+		}
+		return res, nil
+	}
+
+	operation, err := p.getOperation(expr, root)
 	if err != nil {
-		return nil, fmt.Errorf("unable to process expression")
+		return nil, err
 	}
+	return p.engine.ProcessOperation(*operation)
+}
 
-	operator := ops[1]
-	operands := []float64{leftOp, rightOp}
-	if err := p.validator.CheckInput(operator, operands); err != nil {
+// assign stores a result in a variable of the environment.
+func (p *Parser) assign(name string, res *calculator.Result) error {
+	env := p.environment()
+	if env == nil {
+		return fmt.Errorf("variables are not supported")
+	}
+	if err := env.Set(name, calculator.Value{Float: res.Value, Exact: res.Exact}); err != nil {
+		return err
+	}
+	res.Variable = name
+	return nil
+}
+
+// getOperation evaluates the operands of an operation node and
+// builds the operation the calculator should apply to them.
+func (p *Parser) getOperation(expr string, n *operationNode) (*calculator.Operation, error) {
+	exact := p.exactEvaluator() != nil
+	operands := make([]float64, 0, len(n.operands))
+	var exactOperands []*big.Rat
+	for _, o := range n.operands {
+		value, err := p.evaluate(expr, o)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, value.Float)
+		if exact {
+			exactOperands = append(exactOperands, value.Rat())
+		}
+	}
+
+	if err := p.validator.CheckInput(n.operator, operands); err != nil {
 		return nil, err
 	}
 
 	return &calculator.Operation{
 		Expression: expr,
-		Operator:   operator,
+		Operator:   n.operator,
 		Operands:   operands,
+		Exact:      exactOperands,
 	}, nil
 }
+
+// evaluate recursively computes the value of an expression tree node.
+func (p *Parser) evaluate(expr string, n node) (calculator.Value, error) {
+	switch n := n.(type) {
+	case *numberNode:
+		return calculator.Value{Float: n.value, Exact: n.exact}, nil
+	case *variableNode:
+		env := p.environment()
+		if env == nil {
+			return calculator.Value{}, &calculator.UnknownVariableError{Name: n.name}
+		}
+		v, err := env.Get(n.name)
+		if err != nil {
+			return calculator.Value{}, err
+		}
+		return *v, nil
+	case *operationNode:
+		operation, err := p.getOperation(expr, n)
+		if err != nil {
+			return calculator.Value{}, err
+		}
+		if exact := p.exactEvaluator(); exact != nil {
+			res, err := exact.EvaluateExact(*operation)
+			if err != nil {
+				return calculator.Value{}, err
+			}
+			return calculator.NewExactValue(res), nil
+		}
+		res, err := p.engine.Evaluate(*operation)
+		if err != nil {
+			return calculator.Value{}, err
+		}
+		return calculator.Value{Float: *res}, nil
+	default:
+		return calculator.Value{}, fmt.Errorf("unsupported expression at position %d", n.position())
+	}
+}
+
+// environment returns the variables of the engine, or nil if it has none.
+func (p *Parser) environment() *calculator.Environment {
+	if provider, ok := p.engine.(EnvironmentProvider); ok {
+		return provider.Environment()
+	}
+	return nil
+}
+
+// exactEvaluator returns the engine if it evaluates in decimal mode, or nil.
+func (p *Parser) exactEvaluator() ExactEvaluator {
+	if exact, ok := p.engine.(ExactEvaluator); ok && exact.IsExact() {
+		return exact
+	}
+	return nil
+}
+
+// grammar returns the operator grammar of the engine,
+// or the default arithmetic grammar if the engine does not describe one.
+func (p *Parser) grammar() grammar {
+	if lookup, ok := p.engine.(OperatorLookup); ok {
+		return newGrammar(lookup.GetOperations())
+	}
+	return defaultGrammar
+}
@@ -2,9 +2,11 @@ package input_test
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/format"
 	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/input"
 	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/mocks"
 	"github.com/stretchr/testify/assert"
@@ -19,7 +21,7 @@ func TestProcessExpression(t *testing.T) {
 		expr := "2 + 3"
 		operator := "+"
 		operands := []float64{2.0, 3.0}
-		expectedResult := "2 + 3 = 5.5"
+		expectedResult := &calculator.Result{Expression: expr, Operator: operator, Operands: operands, Value: 5.0}
 		engine := mocks.NewOperationProcessor(t)
 		validator := mocks.NewValidationHelper(t)
 		parser := input.NewParser(engine, validator)
@@ -29,7 +31,7 @@ func TestProcessExpression(t *testing.T) {
 			Expression: expr,
 			Operator:   operator,
 			Operands:   operands,
-		}).Return(&expectedResult, nil).Once()
+		}).Return(expectedResult, nil).Once()
 
 		// Act
 		result, error := parser.ProcessExpression(expr)
@@ -37,19 +39,18 @@ func TestProcessExpression(t *testing.T) {
 		// Assert
 		require.Nil(t, error)
 		require.NotNil(t, result)
-		assert.Contains(t, *result, expectedResult)
-		assert.Contains(t, *result, expr)
+		assert.Equal(t, expectedResult, result)
 		validator.AssertExpectations(t)
 		engine.AssertExpectations(t)
 	})
 
-	t.Run("incorrect number of operands", func(t *testing.T) {
+	t.Run("incomplete expression", func(t *testing.T) {
 		// Arrangement
-		expr := "2 + 4 + 6"
+		expr := "2 + 4 +"
 		engine := mocks.NewOperationProcessor(t)
 		validator := mocks.NewValidationHelper(t)
 		parser := input.NewParser(engine, validator)
-		want := "CALCULATION ERROR: expression " + expr + " is invalid: incorrect expression length:got 5, want 3"
+		want := "expression " + expr + " is invalid: unexpected end of expression"
 
 		// Act
 		result, error := parser.ProcessExpression(expr)
@@ -66,7 +67,7 @@ func TestProcessExpression(t *testing.T) {
 		engine := mocks.NewOperationProcessor(t)
 		validator := mocks.NewValidationHelper(t)
 		parser := input.NewParser(engine, validator)
-		want := "CALCULATION ERROR: expression " + expr + " is invalid: unable to process expression"
+		want := "expression " + expr + " is invalid: unexpected operator \"!\" at position 0"
 
 		// Act
 		result, error := parser.ProcessExpression(expr)
@@ -84,7 +85,7 @@ func TestProcessExpression(t *testing.T) {
 		engine := mocks.NewOperationProcessor(t)
 		validator := mocks.NewValidationHelper(t)
 		parser := input.NewParser(engine, validator)
-		want := "CALCULATION ERROR: expression " + expr + " is invalid: unable to process expression"
+		want := "expression " + expr + " is invalid: unexpected operator \"!\" at position 4"
 
 		// Act
 		result, error := parser.ProcessExpression(expr)
@@ -103,7 +104,7 @@ func TestProcessExpression(t *testing.T) {
 		validator := mocks.NewValidationHelper(t)
 		parser := input.NewParser(engine, validator)
 		validator.On("CheckInput", mock.Anything, mock.Anything).Return(fmt.Errorf("invalid operands"))
-		want := "CALCULATION ERROR: expression 4 + 10 is invalid: invalid operands"
+		want := "expression 4 + 10 is invalid: invalid operands"
 
 		// Act
 		result, error := parser.ProcessExpression(expr)
@@ -114,4 +115,364 @@ func TestProcessExpression(t *testing.T) {
 		assert.EqualError(t, error, want)
 		validator.AssertExpectations(t)
 	})
+
+	t.Run("nested operations", func(t *testing.T) {
+		// Arrange
+		expr := "2 + 3 * 4"
+		expectedResult := &calculator.Result{Expression: expr, Operator: "+", Operands: []float64{2.0, 12.0}, Value: 14.0}
+		engine := mocks.NewOperationProcessor(t)
+		validator := mocks.NewValidationHelper(t)
+		parser := input.NewParser(engine, validator)
+		inner := calculator.Operation{
+			Expression: expr,
+			Operator:   "*",
+			Operands:   []float64{3.0, 4.0},
+		}
+		innerResult := 12.0
+
+		validator.On("CheckInput", "*", []float64{3.0, 4.0}).Return(nil).Once()
+		engine.On("Evaluate", inner).Return(&innerResult, nil).Once()
+		validator.On("CheckInput", "+", []float64{2.0, 12.0}).Return(nil).Once()
+		engine.On("ProcessOperation", calculator.Operation{
+			Expression: expr,
+			Operator:   "+",
+			Operands:   []float64{2.0, 12.0},
+		}).Return(expectedResult, nil).Once()
+
+		// Act
+		result, err := parser.ProcessExpression(expr)
+
+		// Assert
+		require.Nil(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, expectedResult, result)
+	})
+}
+
+func TestProcessExpressionGrammar(t *testing.T) {
+	engine := calculator.NewEngine()
+	validator := input.NewValidator(engine)
+	parser := input.NewParser(engine, validator)
+
+	tests := map[string]struct {
+		expr    string
+		want    float64
+		wantErr string
+	}{
+		"precedence":              {expr: "2 + 3 * 4", want: 14.0},
+		"left associativity":      {expr: "10 - 4 - 3", want: 3.0},
+		"division associativity":  {expr: "64 / 4 / 2", want: 8.0},
+		"parentheses":             {expr: "(1 + 2) / 3", want: 1.0},
+		"nested parentheses":      {expr: "((2 + 3) * (4 - 1))", want: 15.0},
+		"no whitespace":           {expr: "2*(3+4)", want: 14.0},
+		"negative literal":        {expr: "2 - -3", want: 5.0},
+		"single number":           {expr: "42", want: 42.0},
+		"exponent literal":        {expr: "1e2 / 4", want: 25.0},
+		"missing closing paren":   {expr: "(1 + 2", wantErr: "missing closing parenthesis for position 0"},
+		"unbalanced closing":      {expr: "1 + 2)", wantErr: "unexpected \")\" at position 5"},
+		"empty expression":        {expr: "  ", wantErr: "empty expression"},
+		"unknown operator":        {expr: "4 % 2", wantErr: "invalid operator: %"},
+		"nested division by zero": {expr: "1 + 2 / 0", wantErr: "cannot divide by zero"},
+		"invalid character":       {expr: "2 + 3 ~ 1", wantErr: "invalid operator: ~"},
+		"bare identifier":         {expr: "2 + a", wantErr: "unknown variable a"},
+		"unary minus":             {expr: "-(2 + 3) * 2", want: -10.0},
+		"double negation":         {expr: "--4", want: 4.0},
+		"unary function":          {expr: "sqrt(16) + abs(-2)", want: 6.0},
+		"variadic function":       {expr: "max(1, 7, 3) - min(4, 2)", want: 5.0},
+		"nested functions":        {expr: "sqrt(max(9, 16))", want: 4.0},
+		"function arity":          {expr: "sqrt(4, 9)", wantErr: "unexpected operands length for sqrt: got 2, want 1"},
+		"unknown function":        {expr: "foo(1)", wantErr: "invalid operator: foo"},
+		"unclosed call":           {expr: "max(1, 2", wantErr: "missing closing parenthesis for position 3"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			result, err := parser.ProcessExpression(tc.expr)
+
+			// Assert
+			if tc.wantErr != "" {
+				require.Nil(t, result)
+				require.NotNil(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.Nil(t, err)
+			require.NotNil(t, result)
+			assert.Equal(t, tc.want, result.Value)
+		})
+	}
+}
+
+func TestProcessExpressionCustomOperators(t *testing.T) {
+	// Arrange
+	pack := []calculator.Definition{
+		{
+			Name: "power", Symbol: "^", Arity: calculator.Binary, Precedence: 3,
+			Associativity: calculator.RightAssociative,
+			Func: func(operands ...float64) (*float64, error) {
+				result := math.Pow(operands[0], operands[1])
+				return &result, nil
+			},
+		},
+		{
+			Name: "modulo", Symbol: "mod", Arity: calculator.Binary, Precedence: 2,
+			Func: func(operands ...float64) (*float64, error) {
+				result := math.Mod(operands[0], operands[1])
+				return &result, nil
+			},
+		},
+		{
+			Name: "shift left", Symbol: "<<", Arity: calculator.Binary, Precedence: 1,
+			Func: func(operands ...float64) (*float64, error) {
+				result := float64(int64(operands[0]) << int64(operands[1]))
+				return &result, nil
+			},
+		},
+	}
+	engine := calculator.NewEngine(calculator.WithOperations(pack...))
+	validator := input.NewValidator(engine)
+	parser := input.NewParser(engine, validator)
+
+	tests := map[string]struct {
+		expr string
+		want float64
+	}{
+		"right associative": {expr: "2 ^ 3 ^ 2", want: 512.0},
+		"precedence":        {expr: "2 * 3 ^ 2", want: 18.0},
+		"word operator":     {expr: "10 mod 4 + 1", want: 3.0},
+		"multi character":   {expr: "1 << 3", want: 8.0},
+		"function call":     {expr: "mod(7, 4)", want: 3.0},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			result, err := parser.ProcessExpression(tc.expr)
+
+			// Assert
+			require.Nil(t, err)
+			require.NotNil(t, result)
+			assert.Equal(t, tc.want, result.Value)
+		})
+	}
+}
+
+func TestProcessExpressionRegisteredLater(t *testing.T) {
+	// Arrange
+	engine := calculator.NewEngine()
+	parser := input.NewParser(engine, input.NewValidator(engine))
+	err := engine.Register(calculator.Definition{
+		Name: "power", Symbol: "^", Arity: calculator.Binary, Precedence: 3, Associativity: calculator.RightAssociative,
+		Func: func(operands ...float64) (*float64, error) {
+			result := math.Pow(operands[0], operands[1])
+			return &result, nil
+		},
+	})
+	require.Nil(t, err)
+
+	// Act
+	result, err := parser.ProcessExpression("2 ^ 10")
+
+	// Assert
+	require.Nil(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, 1024.0, result.Value)
+
+	// Act
+	require.Nil(t, engine.Unregister("^"))
+	_, err = parser.ProcessExpression("2 ^ 10")
+
+	// Assert
+	var unknown *calculator.UnknownOperatorError
+	assert.ErrorAs(t, err, &unknown)
+}
+
+func TestProcessExpressionDecimal(t *testing.T) {
+	// Arrange
+	engine := calculator.NewEngine(calculator.WithDecimal(calculator.DefaultPrecision))
+	validator := input.NewValidator(engine)
+	parser := input.NewParser(engine, validator)
+
+	tests := map[string]struct {
+		expr string
+		want string
+	}{
+		"exact addition":       {expr: "0.1 + 0.2", want: "0.3"},
+		"nested exact":         {expr: "(1 / 3) * 3", want: "1"},
+		"money":                {expr: "19.99 * 3 - 0.07", want: "59.9"},
+		"literal":              {expr: "-0.10", want: "-0.1"},
+		"non-terminating":      {expr: "1 / 3", want: "0.3333333333333333333333333333333333"},
+		"float fallback":       {expr: "cos(0) + 0.1", want: "1.1"},
+		"large integers exact": {expr: "9007199254740993 + 1", want: "9007199254740994"},
+		"beyond float64":       {expr: "1e400 * 1 / 1e399", want: "10"},
+		"beyond float64 sum":   {expr: "1e400 - 1e400 + 2", want: "2"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			result, err := parser.ProcessExpression(tc.expr)
+
+			// Assert
+			require.Nil(t, err)
+			require.NotNil(t, result)
+			require.NotNil(t, result.Exact)
+			assert.Equal(t, tc.want, format.NumberFormat{Precision: calculator.DefaultPrecision, TrimZeros: true}.FormatRat(result.Exact))
+		})
+	}
+}
+
+func TestProcessExpressionVariables(t *testing.T) {
+	t.Run("assignment and use", func(t *testing.T) {
+		// Arrange
+		engine := calculator.NewEngine()
+		validator := input.NewValidator(engine)
+		parser := input.NewParser(engine, validator)
+
+		// Act
+		assigned, err := parser.ProcessExpression("x = 3 * 4")
+		require.Nil(t, err)
+		result, err := parser.ProcessExpression("x / 2")
+
+		// Assert
+		require.Nil(t, err)
+		require.NotNil(t, assigned)
+		require.NotNil(t, result)
+		assert.Equal(t, "x", assigned.Variable)
+		assert.Equal(t, "*", assigned.Operator)
+		assert.Equal(t, 12.0, assigned.Value)
+		assert.Equal(t, 6.0, result.Value)
+	})
+
+	t.Run("constants", func(t *testing.T) {
+		// Arrange
+		engine := calculator.NewEngine()
+		validator := input.NewValidator(engine)
+		parser := input.NewParser(engine, validator)
+
+		// Act
+		result, err := parser.ProcessExpression("2 * pi + e")
+
+		// Assert
+		require.Nil(t, err)
+		require.NotNil(t, result)
+		assert.InDelta(t, 2*math.Pi+math.E, result.Value, 1e-12)
+	})
+
+	t.Run("constants are read-only", func(t *testing.T) {
+		// Arrange
+		engine := calculator.NewEngine()
+		validator := input.NewValidator(engine)
+		parser := input.NewParser(engine, validator)
+
+		// Act
+		result, err := parser.ProcessExpression("pi = 3")
+
+		// Assert
+		require.Nil(t, result)
+		assert.EqualError(t, err, "expression pi = 3 is invalid: cannot assign to constant pi")
+	})
+
+	t.Run("decimal mode keeps variables exact", func(t *testing.T) {
+		// Arrange
+		engine := calculator.NewEngine(calculator.WithDecimal(calculator.DefaultPrecision))
+		validator := input.NewValidator(engine)
+		parser := input.NewParser(engine, validator)
+
+		// Act
+		_, err := parser.ProcessExpression("third = 1 / 3")
+		require.Nil(t, err)
+		result, err := parser.ProcessExpression("third * 3")
+
+		// Assert
+		require.Nil(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, "1", result.Exact.RatString())
+	})
+
+	t.Run("variables without environment", func(t *testing.T) {
+		// Arrange
+		engine := mocks.NewOperationProcessor(t)
+		validator := mocks.NewValidationHelper(t)
+		parser := input.NewParser(engine, validator)
+
+		// Act
+		result, err := parser.ProcessExpression("x")
+
+		// Assert
+		require.Nil(t, result)
+		assert.EqualError(t, err, "expression x is invalid: unknown variable x")
+	})
+
+	t.Run("invalid assignment", func(t *testing.T) {
+		// Arrange
+		engine := calculator.NewEngine()
+		validator := input.NewValidator(engine)
+		parser := input.NewParser(engine, validator)
+
+		// Act
+		result, err := parser.ProcessExpression("x = 1 )")
+
+		// Assert
+		require.Nil(t, result)
+		assert.EqualError(t, err, "expression x = 1 ) is invalid: unexpected \")\" at position 6")
+	})
+}
+
+func TestProcessExpressionErrorTypes(t *testing.T) {
+	engine := calculator.NewEngine()
+	validator := input.NewValidator(engine)
+	parser := input.NewParser(engine, validator)
+
+	t.Run("parse error", func(t *testing.T) {
+		// Act
+		_, err := parser.ProcessExpression("1 + (2 * 3")
+
+		// Assert
+		var exprErr *calculator.ExpressionError
+		require.ErrorAs(t, err, &exprErr)
+		assert.Equal(t, "1 + (2 * 3", exprErr.Expression)
+		var parseErr *calculator.ParseError
+		require.ErrorAs(t, err, &parseErr)
+		assert.Equal(t, 10, parseErr.Pos)
+	})
+
+	t.Run("validation error", func(t *testing.T) {
+		// Act
+		_, err := parser.ProcessExpression("sqrt(1, 2)")
+
+		// Assert
+		var validationErr *calculator.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "sqrt", validationErr.Operator)
+	})
+
+	t.Run("unknown operator", func(t *testing.T) {
+		// Act
+		_, err := parser.ProcessExpression("2 % 3")
+
+		// Assert
+		var unknownErr *calculator.UnknownOperatorError
+		require.ErrorAs(t, err, &unknownErr)
+		assert.Equal(t, "%", unknownErr.Operator)
+	})
+
+	t.Run("division by zero", func(t *testing.T) {
+		// Act
+		_, err := parser.ProcessExpression("1 + 1 / 0")
+
+		// Assert
+		assert.ErrorIs(t, err, calculator.ErrDivisionByZero)
+	})
+
+	t.Run("unknown variable", func(t *testing.T) {
+		// Act
+		_, err := parser.ProcessExpression("y + 1")
+
+		// Assert
+		var unknownErr *calculator.UnknownVariableError
+		require.ErrorAs(t, err, &unknownErr)
+		assert.Equal(t, "y", unknownErr.Name)
+	})
 }
@@ -1,16 +1,26 @@
 package input
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
 
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+)
+
+// OperandCounter reports how many operands each operator accepts.
+type OperandCounter interface {
+	GetNumOperands(operator string) (*calculator.Arity, error)
+}
+
+// Validator checks operations against the operators that counter knows at
+// the time of the check, so operators registered later are accepted too.
 type Validator struct {
-	expectedLength int
-	validOperators []string
+	counter OperandCounter
 }
 
-func NewValidator(expectedLength int, validOps []string) *Validator {
+func NewValidator(counter OperandCounter) *Validator {
 	return &Validator{
-		expectedLength: expectedLength,
-		validOperators: validOps,
+		counter: counter,
 	}
 }
 
@@ -20,21 +30,32 @@ type ValidationHelper interface {
 }
 
 func (v *Validator) CheckInput(operator string, operands []float64) error {
+	arity, err := v.counter.GetNumOperands(operator)
+	if err != nil {
+		return checkOperator(operator, err)
+	}
 	operandsLength := len(operands)
-	if operandsLength != v.expectedLength {
-		return fmt.Errorf("unexpected operands length: got %d, want %d", operandsLength, v.expectedLength)
+	if !arity.Accepts(operandsLength) {
+		return &calculator.ValidationError{
+			Operator: operator,
+			Msg:      fmt.Sprintf("unexpected operands length for %s: got %d, want %s", operator, operandsLength, arity),
+		}
 	}
 
-	return v.checkOperator(operator)
+	return nil
 }
 
-// checkOperator validates the operator is supported
-func (v *Validator) checkOperator(operator string) error {
-	for _, o := range v.validOperators {
-		if o == operator {
-			return nil
-		}
+// checkOperator reports an operator that the counter does not know
+// as invalid, and passes any other error on.
+func checkOperator(operator string, err error) error {
+	var unknown *calculator.UnknownOperatorError
+	if !errors.As(err, &unknown) {
+		return err
 	}
 
-	return fmt.Errorf("invalid operator: %s", operator)
+	return &calculator.ValidationError{
+		Operator: operator,
+		Msg:      fmt.Sprintf("invalid operator: %s", operator),
+		Err:      unknown,
+	}
 }
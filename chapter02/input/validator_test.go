@@ -3,41 +3,70 @@ package input_test
 import (
 	"testing"
 
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
 	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/input"
 )
 
 func TestCheckInput(t *testing.T) {
-	// Arrange
-	expectedLength := 2
-	validOperators := []string{"+"}
-
 	// Act & Assert
 	t.Run("valid case", func(t *testing.T) {
-		v := setup(t, validOperators, expectedLength)
-		err := v.CheckInput(validOperators[0], []float64{2.5, 3.5})
+		v := setup(t)
+		err := v.CheckInput("+", []float64{2.5, 3.5})
 		if err != nil {
 			t.Fatal(err)
 		}
 	})
 
 	t.Run("invalid operands", func(t *testing.T) {
-		v := setup(t, validOperators, expectedLength)
-		err := v.CheckInput(validOperators[0], []float64{2.5, 2.2, 3.3})
+		v := setup(t)
+		err := v.CheckInput("+", []float64{2.5, 2.2, 3.3})
 		if err == nil {
 			t.Fatal(err)
 		}
 	})
 
 	t.Run("invalid operator", func(t *testing.T) {
-		v := setup(t, validOperators, expectedLength)
-		err := v.CheckInput("-", []float64{2.5, 55})
+		v := setup(t)
+		err := v.CheckInput("%", []float64{2.5, 55})
+		if err == nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("valid unary", func(t *testing.T) {
+		v := setup(t)
+		err := v.CheckInput("sqrt", []float64{4})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("invalid unary", func(t *testing.T) {
+		v := setup(t)
+		err := v.CheckInput("sqrt", []float64{4, 9})
+		if err == nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("valid variadic", func(t *testing.T) {
+		v := setup(t)
+		err := v.CheckInput("max", []float64{1, 2, 3, 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("invalid variadic", func(t *testing.T) {
+		v := setup(t)
+		err := v.CheckInput("max", []float64{})
 		if err == nil {
 			t.Fatal(err)
 		}
 	})
 }
 
-func setup(t *testing.T, validOps []string, expectedLength int) *input.Validator {
+func setup(t *testing.T) *input.Validator {
 	t.Helper()
-	return input.NewValidator(expectedLength, validOps)
+	return input.NewValidator(calculator.NewEngine())
 }
@@ -2,22 +2,135 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"strings"
 
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/batch"
 	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/format"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/handlers"
 	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/input"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/repl"
 )
 
 func main() {
 	expr := flag.String("expression", "", "mathematical expression to parse")
+	interactive := flag.Bool("repl", false, "start an interactive session, the default when no expression is given")
+	decimal := flag.Bool("decimal", false, "use arbitrary precision decimal arithmetic")
+	precision := flag.Uint("precision", calculator.DefaultPrecision, "decimal places for inexact results in decimal mode")
+	envFile := flag.String("env", "", "file to load variables from and save them to")
+	file := flag.String("file", "", "file of expressions to evaluate, one per line, or - for stdin")
+	output := flag.String("output", "text", "output format of -file results: text, jsonl or csv")
+	style := flag.String("format", "text", "format of results: "+strings.Join(format.Formatters, ", "))
+	digits := flag.Int("digits", 2, "decimal places of displayed results, -1 for the shortest representation")
+	scientific := flag.Bool("scientific", false, "display results in scientific notation")
+	thousands := flag.String("thousands", "", "separator between groups of thousands, such as ,")
+	locale := flag.String("locale", "", "language tag whose separators are used, such as de or fr")
+	addr := flag.String("http", "", "serve the HTTP API on the given address, such as :8080")
 	flag.Parse()
 
-	engine := calculator.NewEngine()
-	validator := input.NewValidator(engine.GetNumOperands(), engine.GetValidOperators())
+	var opts []calculator.Option
+	if *decimal {
+		opts = append(opts, calculator.WithDecimal(*precision))
+	}
+	if *addr != "" {
+		router := handlers.ConfigureServer(handlers.NewHandler(opts...))
+		log.Printf("Listening on %s...\n", *addr)
+		log.Fatal(http.ListenAndServe(*addr, router))
+	}
+
+	engine := calculator.NewEngine(opts...)
+	if *envFile != "" {
+		if err := engine.Environment().Load(*envFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	validator := input.NewValidator(engine)
 	parser := input.NewParser(engine, validator)
-	result, err := parser.ProcessExpression(*expr)
+
+	number := format.NumberFormat{
+		Precision:          *digits,
+		Scientific:         *scientific,
+		ThousandsSeparator: *thousands,
+	}
+	if *decimal && !isFlagSet("digits") {
+		number.Precision = engine.Precision()
+		number.TrimZeros = true
+	}
+	if *locale != "" {
+		var err error
+		if number, err = number.WithLocale(*locale); err != nil {
+			log.Fatal(err)
+		}
+		if isFlagSet("thousands") {
+			number.ThousandsSeparator = *thousands
+		}
+	}
+	formatter, err := format.NewFormatter(*style, number)
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println(*result)
+
+	exitCode := 0
+	switch {
+	case *file != "":
+		summary, err := runBatch(parser, *file, *output, formatter, format.Machine(engine.IsExact(), engine.Precision()))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintln(os.Stderr, summary)
+		if summary.Failed > 0 {
+			exitCode = 1
+		}
+	case *interactive || *expr == "":
+		if err := repl.New(parser, engine, formatter).Run(os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		result, err := parser.ProcessExpression(*expr)
+		if err != nil {
+			log.Fatal(formatter.FormatError(err))
+		}
+		log.Println(formatter.Format(result))
+	}
+
+	if *envFile != "" {
+		if err := engine.Environment().Save(*envFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	os.Exit(exitCode)
+}
+
+// runBatch evaluates every expression of the given file, or stdin for -.
+func runBatch(parser *input.Parser, file, output string, formatter format.Formatter, number format.NumberFormat) (*batch.Summary, error) {
+	w, err := batch.NewWriter(output, os.Stdout, formatter, number)
+	if err != nil {
+		return nil, err
+	}
+
+	in := os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+	return batch.Run(parser, in, w)
+}
+
+// isFlagSet reports whether the named flag was given on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
@@ -12,24 +12,54 @@ type OperationProcessor struct {
 	mock.Mock
 }
 
+// Evaluate provides a mock function with given fields: operation
+func (_m *OperationProcessor) Evaluate(operation calculator.Operation) (*float64, error) {
+	ret := _m.Called(operation)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Evaluate")
+	}
+
+	var r0 *float64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(calculator.Operation) (*float64, error)); ok {
+		return rf(operation)
+	}
+	if rf, ok := ret.Get(0).(func(calculator.Operation) *float64); ok {
+		r0 = rf(operation)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*float64)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(calculator.Operation) error); ok {
+		r1 = rf(operation)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ProcessOperation provides a mock function with given fields: operation
-func (_m *OperationProcessor) ProcessOperation(operation calculator.Operation) (*string, error) {
+func (_m *OperationProcessor) ProcessOperation(operation calculator.Operation) (*calculator.Result, error) {
 	ret := _m.Called(operation)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ProcessOperation")
 	}
 
-	var r0 *string
+	var r0 *calculator.Result
 	var r1 error
-	if rf, ok := ret.Get(0).(func(calculator.Operation) (*string, error)); ok {
+	if rf, ok := ret.Get(0).(func(calculator.Operation) (*calculator.Result, error)); ok {
 		return rf(operation)
 	}
-	if rf, ok := ret.Get(0).(func(calculator.Operation) *string); ok {
+	if rf, ok := ret.Get(0).(func(calculator.Operation) *calculator.Result); ok {
 		r0 = rf(operation)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*string)
+			r0 = ret.Get(0).(*calculator.Result)
 		}
 	}
 
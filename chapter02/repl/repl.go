@@ -0,0 +1,120 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/format"
+)
+
+const prompt = "> "
+
+// ExpressionProcessor processes a single expression, such as input.Parser.
+type ExpressionProcessor interface {
+	ProcessExpression(expr string) (*calculator.Result, error)
+}
+
+// Session is the calculator state that the REPL commands expose, such as calculator.Engine.
+type Session interface {
+	Environment() *calculator.Environment
+	GetValidOperators() []string
+}
+
+// REPL reads expressions line by line, evaluates them and prints the results.
+type REPL struct {
+	parser    ExpressionProcessor
+	session   Session
+	formatter format.Formatter
+	history   []string
+}
+
+// New creates a REPL that evaluates expressions with the given parser
+// and prints the results with the given formatter.
+func New(parser ExpressionProcessor, session Session, formatter format.Formatter) *REPL {
+	return &REPL{
+		parser:    parser,
+		session:   session,
+		formatter: formatter,
+	}
+}
+
+// History returns the expressions evaluated so far, oldest first.
+func (r *REPL) History() []string {
+	return r.history
+}
+
+// Run reads lines from in until it is exhausted or :quit is entered.
+// Evaluation errors are reported to out and do not stop the loop.
+func (r *REPL) Run(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprint(out, prompt)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, ":"):
+			if quit := r.command(line, out); quit {
+				return nil
+			}
+		default:
+			r.history = append(r.history, line)
+			result, err := r.parser.ProcessExpression(line)
+			if err != nil {
+				fmt.Fprintln(out, r.formatter.FormatError(err))
+				break
+			}
+			fmt.Fprintln(out, r.formatter.Format(result))
+		}
+		fmt.Fprint(out, prompt)
+	}
+	fmt.Fprintln(out)
+	return scanner.Err()
+}
+
+// command runs a REPL command and reports whether the REPL should stop.
+func (r *REPL) command(line string, out io.Writer) bool {
+	switch line {
+	case ":quit", ":q":
+		return true
+	case ":help", ":h":
+		r.help(out)
+	case ":vars":
+		r.vars(out)
+	case ":history":
+		for i, h := range r.history {
+			fmt.Fprintf(out, "%d: %s\n", i+1, h)
+		}
+	default:
+		fmt.Fprintf(out, "unknown command %s, type :help for a list of commands\n", line)
+	}
+	return false
+}
+
+func (r *REPL) help(out io.Writer) {
+	fmt.Fprintln(out, "Enter an expression such as (1 + 2) * 3 or x = sqrt(16).")
+	fmt.Fprintln(out, "Operators and functions:", strings.Join(r.session.GetValidOperators(), " "))
+	fmt.Fprintln(out, "Commands:")
+	fmt.Fprintln(out, "  :help     show this help")
+	fmt.Fprintln(out, "  :vars     list the variables of the session")
+	fmt.Fprintln(out, "  :history  list the expressions entered so far")
+	fmt.Fprintln(out, "  :quit     leave the calculator")
+}
+
+func (r *REPL) vars(out io.Writer) {
+	env := r.session.Environment()
+	names := env.Names()
+	if len(names) == 0 {
+		fmt.Fprintln(out, "no variables defined")
+		return
+	}
+	for _, n := range names {
+		v, err := env.Get(n)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(out, "%s = %s\n", n, v)
+	}
+}
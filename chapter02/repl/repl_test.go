@@ -0,0 +1,75 @@
+package repl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/calculator"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/format"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/input"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter02/repl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setup(t *testing.T) *repl.REPL {
+	t.Helper()
+	engine := calculator.NewEngine()
+	validator := input.NewValidator(engine)
+	parser := input.NewParser(engine, validator)
+	return repl.New(parser, engine, format.Text{Number: format.NumberFormat{Precision: 2}})
+}
+
+func TestRun(t *testing.T) {
+	t.Run("evaluates lines and keeps going after errors", func(t *testing.T) {
+		// Arrange
+		r := setup(t)
+		in := strings.NewReader("x = 3 * 4\n1 / 0\n\nx / 2\n")
+		var out bytes.Buffer
+
+		// Act
+		err := r.Run(in, &out)
+
+		// Assert
+		require.Nil(t, err)
+		assert.Contains(t, out.String(), "CALCULATION SUCCESS: x = 3 * 4 = 12.00")
+		assert.Contains(t, out.String(), "CALCULATION ERROR: expression 1 / 0 is invalid: cannot divide by zero")
+		assert.Contains(t, out.String(), "CALCULATION SUCCESS: x / 2 = 6.00")
+		assert.Equal(t, []string{"x = 3 * 4", "1 / 0", "x / 2"}, r.History())
+	})
+
+	t.Run("quit stops reading", func(t *testing.T) {
+		// Arrange
+		r := setup(t)
+		in := strings.NewReader("1 + 1\n:quit\n2 + 2\n")
+		var out bytes.Buffer
+
+		// Act
+		err := r.Run(in, &out)
+
+		// Assert
+		require.Nil(t, err)
+		assert.Contains(t, out.String(), "1 + 1 = 2.00")
+		assert.NotContains(t, out.String(), "2 + 2")
+		assert.Equal(t, []string{"1 + 1"}, r.History())
+	})
+
+	t.Run("commands", func(t *testing.T) {
+		// Arrange
+		r := setup(t)
+		in := strings.NewReader(":vars\nb = 2\na = 1\n:vars\n:history\n:help\n:nope\n")
+		var out bytes.Buffer
+
+		// Act
+		err := r.Run(in, &out)
+
+		// Assert
+		require.Nil(t, err)
+		assert.Contains(t, out.String(), "no variables defined")
+		assert.Contains(t, out.String(), "a = 1\nb = 2\n")
+		assert.Contains(t, out.String(), "1: b = 2\n2: a = 1\n")
+		assert.Contains(t, out.String(), "Operators and functions: * + - / abs cos")
+		assert.Contains(t, out.String(), "unknown command :nope")
+	})
+}
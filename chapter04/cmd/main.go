@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	_ "embed"
 
 	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
 	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/handlers"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
 )
 
 //go:embed books.json
@@ -19,20 +28,200 @@ var booksFile []byte
 //go:embed users.json
 var usersFile []byte
 
+// shutdownTimeout is how long the requests in flight are given to finish
+// once the server is asked to stop.
+const shutdownTimeout = 30 * time.Second
+
 func main() {
+	logger := newLogger()
+	slog.SetDefault(logger)
 	port, ok := os.LookupEnv("BOOKSWAP_PORT")
 	if !ok {
-		log.Fatal("$BOOKSWAP_PORT not found")
+		fatal("$BOOKSWAP_PORT not found", nil)
 	}
 	books, users := importInitial()
-	ps := db.NewPostingService()
-	b := db.NewBookService(books, ps)
-	u := db.NewUserService(users, b)
-	h := handlers.NewHandler(b, u)
+	bookRepo, userRepo, swapRepo, eventRepo, credentialRepo := openRepositories(books, users)
+	ps := newPostingService(logger)
+	reg := metrics.NewRegistry()
+	b := db.NewBookServiceWithRepository(bookRepo, eventRepo, ps, logger, reg)
+	u := db.NewUserServiceWithRepository(userRepo, b, logger, reg)
+	s := db.NewSwapService(swapRepo, b)
+	a := db.NewAuthService(credentialRepo, db.AuthConfig{Secret: tokenSecret(), Admins: admins()})
+	h := handlers.NewHandler(b, u, s, a, logger, reg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dispatchCtx, stopDispatch := context.WithCancel(context.Background())
+	dispatched := make(chan struct{})
+	go func() {
+		b.Outbox().Run(dispatchCtx, 5*time.Second)
+		close(dispatched)
+	}()
+
+	srv := &http.Server{
+		Addr:              fmt.Sprint(":", port),
+		Handler:           handlers.ConfigureServer(h),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+	served := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "port", port)
+		served <- srv.ListenAndServe()
+	}()
+
+	var failed bool
+	select {
+	case err := <-served:
+		slog.Error("server stopped", "error", err)
+		failed = true
+	case <-ctx.Done():
+		stop()
+		slog.Info("shutting down", "timeout", shutdownTimeout)
+		h.Drain()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("requests cut off by shutdown", "error", err)
+			failed = true
+		}
+	}
+
+	// The swaps are saved by now. The dispatcher stops before its next
+	// delivery but finishes the one under way, so it is recorded before
+	// the repositories close. Pending events are delivered after a restart.
+	stopDispatch()
+	<-dispatched
+	if !closeRepositories(bookRepo, userRepo, swapRepo, eventRepo, credentialRepo) {
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
+	slog.Info("stopped")
+}
+
+// newLogger logs JSON records to stdout at $BOOKSWAP_LOG_LEVEL, such as DEBUG,
+// which defaults to INFO. Records logged with the context of a request carry its ID.
+func newLogger() *slog.Logger {
+	var level slog.Level
+	if name, ok := os.LookupEnv("BOOKSWAP_LOG_LEVEL"); ok {
+		if err := level.UnmarshalText([]byte(name)); err != nil {
+			fatal("invalid $BOOKSWAP_LOG_LEVEL", err)
+		}
+	}
+	return slog.New(db.NewLogHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})))
+}
+
+// fatal logs msg and err, if any, and exits.
+func fatal(msg string, err error) {
+	if err != nil {
+		slog.Error(msg, "error", err)
+	} else {
+		slog.Error(msg)
+	}
+	os.Exit(1)
+}
 
-	router := handlers.ConfigureServer(h)
-	log.Printf("Listening on :%s...\n", port)
-	log.Fatal(http.ListenAndServe(fmt.Sprint(":", port), router))
+// openRepositories stores books, users, swaps, outbox events and credentials in $BOOKSWAP_DATA_DIR
+// when it is set, seeding new logs with the initial data, and in memory otherwise.
+func openRepositories(books []db.Book, users []db.User) (db.BookRepository, db.UserRepository, db.SwapRepository, db.OutboxRepository, db.CredentialRepository) {
+	dir, ok := os.LookupEnv("BOOKSWAP_DATA_DIR")
+	if !ok {
+		slog.Warn("$BOOKSWAP_DATA_DIR not found, data will not be persisted")
+		return db.NewMemoryBookRepository(books), db.NewMemoryUserRepository(users), db.NewMemorySwapRepository(nil),
+			db.NewMemoryOutboxRepository(nil), db.NewMemoryCredentialRepository(nil)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fatal("cannot create $BOOKSWAP_DATA_DIR", err)
+	}
+	bookRepo, err := db.NewFileBookRepository(filepath.Join(dir, "books.log"), books)
+	if err != nil {
+		fatal("cannot open books log", err)
+	}
+	userRepo, err := db.NewFileUserRepository(filepath.Join(dir, "users.log"), users)
+	if err != nil {
+		fatal("cannot open users log", err)
+	}
+	swapRepo, err := db.NewFileSwapRepository(filepath.Join(dir, "swaps.log"))
+	if err != nil {
+		fatal("cannot open swaps log", err)
+	}
+	eventRepo, err := db.NewFileOutboxRepository(filepath.Join(dir, "outbox.log"))
+	if err != nil {
+		fatal("cannot open outbox log", err)
+	}
+	credentialRepo, err := db.NewFileCredentialRepository(filepath.Join(dir, "credentials.log"))
+	if err != nil {
+		fatal("cannot open credentials log", err)
+	}
+	slog.Info("persisting data", "dir", dir)
+	return bookRepo, userRepo, swapRepo, eventRepo, credentialRepo
+}
+
+// closeRepositories flushes and closes the repositories that are backed by files,
+// and reports whether all of them were closed.
+func closeRepositories(repos ...any) bool {
+	ok := true
+	for _, r := range repos {
+		if c, isCloser := r.(io.Closer); isCloser {
+			if err := c.Close(); err != nil {
+				slog.Error("cannot close repository", "error", err)
+				ok = false
+			}
+		}
+	}
+	return ok
+}
+
+// tokenSecret returns the secret that signs bearer tokens, $BOOKSWAP_TOKEN_SECRET when it is set.
+// Otherwise a random secret is generated and tokens are invalidated by restarts.
+func tokenSecret() []byte {
+	if secret, ok := os.LookupEnv("BOOKSWAP_TOKEN_SECRET"); ok {
+		return []byte(secret)
+	}
+	slog.Warn("$BOOKSWAP_TOKEN_SECRET not found, tokens will not survive restarts")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		fatal("cannot generate token secret", err)
+	}
+	return secret
+}
+
+// admins returns the IDs of the users that administer the service, listed
+// in $BOOKSWAP_ADMINS separated by commas. Administrators manage the outbox and
+// set the passwords of the seeded users, who cannot log in until then.
+func admins() []string {
+	var ids []string
+	for _, id := range strings.Split(os.Getenv("BOOKSWAP_ADMINS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		slog.Warn("$BOOKSWAP_ADMINS not found, the outbox and seeded users cannot be managed")
+	}
+	return ids
+}
+
+// newPostingService calls the posting endpoint at $BOOKSWAP_POSTING_URL when it is set,
+// and stubs posting otherwise.
+func newPostingService(logger *slog.Logger) db.PostingService {
+	baseURL, ok := os.LookupEnv("BOOKSWAP_POSTING_URL")
+	if !ok {
+		slog.Warn("$BOOKSWAP_POSTING_URL not found, posting is stubbed")
+		return db.NewPostingService()
+	}
+	return db.NewHTTPPostingService(db.PostingConfig{
+		BaseURL:    baseURL,
+		MaxRetries: 3,
+		Logger:     logger,
+	})
 }
 
 func importInitial() ([]db.Book, []db.User) {
@@ -41,11 +230,11 @@ func importInitial() ([]db.Book, []db.User) {
 
 	err := json.Unmarshal(booksFile, &books)
 	if err != nil {
-		log.Fatal(err)
+		fatal("cannot import initial books", err)
 	}
 	err = json.Unmarshal(usersFile, &users)
 	if err != nil {
-		log.Fatal(err)
+		fatal("cannot import initial users", err)
 	}
 
 	return books, users
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportInitial(t *testing.T) {
+	// Act
+	books, users := importInitial()
+
+	// Assert
+	require.NotEmpty(t, books)
+	require.NotEmpty(t, users)
+	for _, b := range books {
+		assert.Nil(t, b.Validate(), b.ID)
+	}
+	for _, u := range users {
+		assert.Nil(t, u.Validate(), u.ID)
+	}
+}
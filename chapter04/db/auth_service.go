@@ -0,0 +1,192 @@
+package db
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidCredentials = NewError(ErrUnauthenticated, "invalid_credentials", "invalid user or password")
+	ErrInvalidToken       = NewError(ErrUnauthenticated, "invalid_token", "invalid token")
+)
+
+// MinPasswordLength is the number of characters a password needs at least.
+const MinPasswordLength = 8
+
+// Credential is the stretched password of a user. Passwords are stretched
+// with PBKDF2-HMAC-SHA256 and a random salt; the iterations are kept so that
+// they can be raised without invalidating the existing passwords.
+type Credential struct {
+	UserID     string `json:"user_id"`
+	Salt       []byte `json:"salt"`
+	Hash       []byte `json:"hash"`
+	Iterations int    `json:"iterations"`
+}
+
+// AuthConfig configures an AuthService.
+type AuthConfig struct {
+	// Secret signs the tokens. It should be at least 32 random bytes.
+	Secret []byte
+	// TokenTTL is how long tokens are valid. Defaults to 24 hours.
+	TokenTTL time.Duration
+	// Iterations is the number of PBKDF2 iterations for new passwords.
+	// Defaults to 600,000.
+	Iterations int
+	// Admins are the IDs of the users that may administer the service,
+	// such as inspecting and replaying the outbox.
+	Admins []string
+}
+
+// AuthService checks the passwords of users and issues the signed bearer
+// tokens, HS256 JSON Web Tokens, that identify them on later requests.
+type AuthService struct {
+	cfg         AuthConfig
+	credentials CredentialRepository
+	now         func() time.Time
+}
+
+// NewAuthService creates an AuthService that stores credentials in repo.
+func NewAuthService(repo CredentialRepository, cfg AuthConfig) *AuthService {
+	if cfg.TokenTTL == 0 {
+		cfg.TokenTTL = 24 * time.Hour
+	}
+	if cfg.Iterations == 0 {
+		cfg.Iterations = 600_000
+	}
+	return &AuthService{
+		cfg:         cfg,
+		credentials: repo,
+		now:         time.Now,
+	}
+}
+
+// ValidatePassword returns a *ValidationError if the password is too short.
+func ValidatePassword(password string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return &ValidationError{Errors: []FieldError{{
+			Field:   "password",
+			Message: fmt.Sprintf("must be at least %d characters", MinPasswordLength),
+		}}}
+	}
+	return nil
+}
+
+// SetPassword sets or replaces the password of a user.
+func (as *AuthService) SetPassword(userID, password string) error {
+	if err := ValidatePassword(password); err != nil {
+		return err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	return as.credentials.Save(Credential{
+		UserID:     userID,
+		Salt:       salt,
+		Hash:       stretch(password, salt, as.cfg.Iterations),
+		Iterations: as.cfg.Iterations,
+	})
+}
+
+// Login checks the password of a user and returns a token for them.
+func (as *AuthService) Login(userID, password string) (string, error) {
+	c, ok := as.credentials.Get(userID)
+	if !ok {
+		// Stretch anyway, so that unknown users take as long as wrong passwords.
+		stretch(password, nil, as.cfg.Iterations)
+		return "", ErrInvalidCredentials
+	}
+	if !hmac.Equal(stretch(password, c.Salt, c.Iterations), c.Hash) {
+		return "", ErrInvalidCredentials
+	}
+	return as.Token(userID)
+}
+
+// IsAdmin reports whether a user may administer the service.
+func (as *AuthService) IsAdmin(userID string) bool {
+	return userID != "" && slices.Contains(as.cfg.Admins, userID)
+}
+
+// Token issues a token for a user.
+func (as *AuthService) Token(userID string) (string, error) {
+	now := as.now()
+	payload, err := json.Marshal(claims{
+		Subject:   userID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(as.cfg.TokenTTL).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(as.sign(signed)), nil
+}
+
+// Verify checks the signature and expiry of a token and returns the ID of its user.
+func (as *AuthService) Verify(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return "", fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, as.sign(parts[0]+"."+parts[1])) {
+		return "", fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil || c.Subject == "" {
+		return "", fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if as.now().Unix() >= c.ExpiresAt {
+		return "", fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+	return c.Subject, nil
+}
+
+// tokenHeader is the encoded JWT header of every token, {"alg":"HS256","typ":"JWT"}.
+// Tokens with any other header, such as "alg":"none", are rejected.
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// claims is the payload of a token.
+type claims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func (as *AuthService) sign(s string) []byte {
+	mac := hmac.New(sha256.New, as.cfg.Secret)
+	mac.Write([]byte(s))
+	return mac.Sum(nil)
+}
+
+// stretch derives a 32 byte key from a password with PBKDF2-HMAC-SHA256
+// (RFC 8018). A single block of output is all that SHA-256 needs.
+func stretch(password string, salt []byte, iterations int) []byte {
+	prf := hmac.New(sha256.New, []byte(password))
+	prf.Write(salt)
+	prf.Write(binary.BigEndian.AppendUint32(nil, 1))
+	u := prf.Sum(nil)
+	key := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
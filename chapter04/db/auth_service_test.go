@@ -0,0 +1,112 @@
+package db_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuthService(cfg db.AuthConfig) *db.AuthService {
+	if cfg.Secret == nil {
+		cfg.Secret = []byte("secret")
+	}
+	cfg.Iterations = 1000
+	return db.NewAuthService(db.NewMemoryCredentialRepository(nil), cfg)
+}
+
+func TestLogin(t *testing.T) {
+	// Arrange
+	userID := uuid.NewString()
+	as := newAuthService(db.AuthConfig{})
+	require.Nil(t, as.SetPassword(userID, "correct horse"))
+
+	tests := map[string]struct {
+		userID   string
+		password string
+		wantErr  error
+	}{
+		"correct password": {userID: userID, password: "correct horse"},
+		"wrong password":   {userID: userID, password: "battery staple", wantErr: db.ErrInvalidCredentials},
+		"unknown user":     {userID: uuid.NewString(), password: "correct horse", wantErr: db.ErrInvalidCredentials},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			token, err := as.Login(tc.userID, tc.password)
+
+			// Assert
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				assert.Empty(t, token)
+				return
+			}
+			require.Nil(t, err)
+			subject, err := as.Verify(token)
+			require.Nil(t, err)
+			assert.Equal(t, tc.userID, subject)
+		})
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	adminID := uuid.NewString()
+	as := newAuthService(db.AuthConfig{Admins: []string{adminID}})
+
+	assert.True(t, as.IsAdmin(adminID))
+	assert.False(t, as.IsAdmin(uuid.NewString()))
+	assert.False(t, as.IsAdmin(""))
+}
+
+func TestSetShortPassword(t *testing.T) {
+	// Arrange
+	as := newAuthService(db.AuthConfig{})
+
+	// Act
+	err := as.SetPassword(uuid.NewString(), "short")
+
+	// Assert
+	var verr *db.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "password", verr.Errors[0].Field)
+}
+
+func TestVerify(t *testing.T) {
+	// Arrange
+	userID := uuid.NewString()
+	as := newAuthService(db.AuthConfig{})
+	token, err := as.Token(userID)
+	require.Nil(t, err)
+	parts := strings.Split(token, ".")
+	expired, err := newAuthService(db.AuthConfig{TokenTTL: -time.Minute}).Token(userID)
+	require.Nil(t, err)
+	other, err := newAuthService(db.AuthConfig{Secret: []byte("other")}).Token(userID)
+	require.Nil(t, err)
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin","exp":99999999999}`))
+
+	tests := map[string]string{
+		"malformed":      "not-a-token",
+		"expired":        expired,
+		"other secret":   other,
+		"no algorithm":   none + "." + parts[1] + ".",
+		"forged subject": parts[0] + "." + forged + "." + parts[2],
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			subject, err := as.Verify(token)
+
+			// Assert
+			assert.ErrorIs(t, err, db.ErrInvalidToken)
+			assert.Empty(t, subject)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultPageSize is the number of books in a page when no limit is given.
+	DefaultPageSize = 20
+	// MaxPageSize is the largest number of books in a page.
+	MaxPageSize = 100
+)
+
+var ErrInvalidQuery = NewError(ErrInvalid, "invalid_query", "invalid query")
+
+// BookQuery selects, orders and pages books.
+type BookQuery struct {
+	// Search matches books whose name or author contain every word of it.
+	Search string
+	// Author matches books by the author, ignoring case.
+	Author string
+	// OwnerIDs matches books owned by one of the users. A nil slice
+	// matches every owner and an empty slice none.
+	OwnerIDs []string
+	// Statuses matches books with one of the statuses, every status if empty.
+	Statuses []BookStatus
+	// Sort is name, author or created, prefixed with - for descending order.
+	// Defaults to name.
+	Sort string
+	// Limit is the page size, DefaultPageSize when zero.
+	Limit int
+	// Cursor is the NextCursor of the previous page.
+	Cursor string
+}
+
+// BookPage is a page of the books matching a query.
+type BookPage struct {
+	Books []Book
+	// NextCursor continues the query on the next page, empty on the last page.
+	NextCursor string
+}
+
+// cursor marks the last book of a page.
+type cursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   string `json:"i"`
+}
+
+// sortTimeLayout formats times with a fixed number of fractional digits,
+// so that they compare in chronological order as strings.
+const sortTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// sortKeys returns the value that books are ordered by for each sort field.
+var sortKeys = map[string]func(b Book) string{
+	"name":    func(b Book) string { return strings.ToLower(b.Name) },
+	"author":  func(b Book) string { return strings.ToLower(b.Author) },
+	"created": func(b Book) string { return b.CreatedAt.UTC().Format(sortTimeLayout) },
+}
+
+// Query returns a page of the books matching q.
+func (bs *BookService) Query(ctx context.Context, q BookQuery) (*BookPage, error) {
+	if q.Sort == "" {
+		q.Sort = "name"
+	}
+	field, descending := strings.TrimPrefix(q.Sort, "-"), strings.HasPrefix(q.Sort, "-")
+	key, ok := sortKeys[field]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown sort %s", ErrInvalidQuery, q.Sort)
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit < 0 || q.Limit > MaxPageSize {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxPageSize)
+	}
+	after, err := decodeCursor(q.Cursor, q.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	// less orders books by their sort key, then by ID to keep pages stable.
+	less := func(ka, ida, kb, idb string) bool {
+		if ka == kb {
+			return ida < idb
+		}
+		return (ka < kb) != descending
+	}
+	var items = make([]Book, 0)
+	for _, b := range bs.books.List() {
+		if q.matches(b) {
+			items = append(items, b)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return less(key(items[i]), items[i].ID, key(items[j]), items[j].ID)
+	})
+
+	if after != nil {
+		start := sort.Search(len(items), func(i int) bool {
+			return less(after.Key, after.ID, key(items[i]), items[i].ID)
+		})
+		items = items[start:]
+	}
+
+	page := &BookPage{Books: items}
+	if len(items) > q.Limit {
+		page.Books = items[:q.Limit]
+		last := page.Books[q.Limit-1]
+		page.NextCursor = encodeCursor(cursor{Sort: q.Sort, Key: key(last), ID: last.ID})
+	}
+	return page, nil
+}
+
+func (q BookQuery) matches(b Book) bool {
+	if b.DeletedAt != nil {
+		return false
+	}
+	if len(q.Statuses) > 0 && !containsStatus(q.Statuses, b.Status) {
+		return false
+	}
+	if q.OwnerIDs != nil && !containsString(q.OwnerIDs, b.OwnerID) {
+		return false
+	}
+	if q.Author != "" && !strings.EqualFold(q.Author, b.Author) {
+		return false
+	}
+	text := strings.ToLower(b.Name + " " + b.Author)
+	for _, word := range strings.Fields(strings.ToLower(q.Search)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsStatus(statuses []BookStatus, s BookStatus) bool {
+	for _, status := range statuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the position encoded in token, nil for an empty token.
+// Cursors only continue the sort order they were created for.
+func decodeCursor(token, sort string) (*cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if c.Sort != sort {
+		return nil, fmt.Errorf("%w: cursor was created for sort %s", ErrInvalidQuery, c.Sort)
+	}
+	return &c, nil
+}
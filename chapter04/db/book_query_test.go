@@ -0,0 +1,148 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func queryBooks() []db.Book {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []db.Book{
+		{ID: "1", Name: "The Hobbit", Author: "J.R.R. Tolkien", OwnerID: "alice", Status: db.Available, CreatedAt: created.Add(3 * time.Hour)},
+		{ID: "2", Name: "Dune", Author: "Frank Herbert", OwnerID: "bob", Status: db.Available, CreatedAt: created.Add(1 * time.Hour)},
+		{ID: "3", Name: "Children of Dune", Author: "Frank Herbert", OwnerID: "alice", Status: db.Swapped, CreatedAt: created.Add(2 * time.Hour)},
+		{ID: "4", Name: "Emma", Author: "Jane Austen", OwnerID: "carol", Status: db.Available, CreatedAt: created},
+	}
+}
+
+func ids(books []db.Book) []string {
+	var items []string
+	for _, b := range books {
+		items = append(items, b.ID)
+	}
+	return items
+}
+
+func TestQuery(t *testing.T) {
+	// Arrange
+	bs := db.NewBookService(queryBooks(), nil)
+
+	tests := map[string]struct {
+		query db.BookQuery
+		want  []string
+	}{
+		"all books by name": {
+			query: db.BookQuery{},
+			want:  []string{"3", "2", "4", "1"},
+		},
+		"search name and author": {
+			query: db.BookQuery{Search: "dune herbert"},
+			want:  []string{"3", "2"},
+		},
+		"search ignores case": {
+			query: db.BookQuery{Search: "TOLKIEN"},
+			want:  []string{"1"},
+		},
+		"author": {
+			query: db.BookQuery{Author: "frank herbert", Sort: "-name"},
+			want:  []string{"2", "3"},
+		},
+		"owners": {
+			query: db.BookQuery{OwnerIDs: []string{"alice", "carol"}},
+			want:  []string{"3", "4", "1"},
+		},
+		"no owners": {
+			query: db.BookQuery{OwnerIDs: []string{}},
+		},
+		"statuses": {
+			query: db.BookQuery{Statuses: []db.BookStatus{db.Available}, Sort: "author"},
+			want:  []string{"2", "1", "4"},
+		},
+		"newest first": {
+			query: db.BookQuery{Sort: "-created"},
+			want:  []string{"1", "3", "2", "4"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			page, err := bs.Query(context.Background(), tc.query)
+
+			// Assert
+			require.Nil(t, err)
+			assert.Equal(t, tc.want, ids(page.Books))
+			assert.Empty(t, page.NextCursor)
+		})
+	}
+}
+
+func TestQueryByCreatedWithinASecond(t *testing.T) {
+	// Arrange
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bs := db.NewBookService([]db.Book{
+		{ID: "1", Status: db.Available, CreatedAt: created.Add(123 * time.Millisecond)},
+		{ID: "2", Status: db.Available, CreatedAt: created.Add(500 * time.Millisecond)},
+		{ID: "3", Status: db.Available, CreatedAt: created},
+		{ID: "4", Status: db.Available, CreatedAt: created.Add(120 * time.Millisecond)},
+	}, nil)
+
+	// Act
+	page, err := bs.Query(context.Background(), db.BookQuery{Sort: "created", Limit: 2})
+	require.Nil(t, err)
+	next, err := bs.Query(context.Background(), db.BookQuery{Sort: "created", Limit: 2, Cursor: page.NextCursor})
+
+	// Assert
+	require.Nil(t, err)
+	assert.Equal(t, []string{"3", "4"}, ids(page.Books))
+	assert.Equal(t, []string{"1", "2"}, ids(next.Books))
+}
+
+func TestQueryPages(t *testing.T) {
+	// Arrange
+	bs := db.NewBookService(queryBooks(), nil)
+	query := db.BookQuery{Sort: "created", Limit: 3}
+
+	// Act
+	first, err := bs.Query(context.Background(), query)
+	require.Nil(t, err)
+	query.Cursor = first.NextCursor
+	second, err := bs.Query(context.Background(), query)
+	require.Nil(t, err)
+
+	// Assert
+	assert.Equal(t, []string{"4", "2", "3"}, ids(first.Books))
+	assert.NotEmpty(t, first.NextCursor)
+	assert.Equal(t, []string{"1"}, ids(second.Books))
+	assert.Empty(t, second.NextCursor)
+}
+
+func TestQueryInvalid(t *testing.T) {
+	// Arrange
+	bs := db.NewBookService(queryBooks(), nil)
+	page, err := bs.Query(context.Background(), db.BookQuery{Limit: 1})
+	require.Nil(t, err)
+
+	tests := map[string]db.BookQuery{
+		"unknown sort":         {Sort: "owner"},
+		"negative limit":       {Limit: -1},
+		"limit too large":      {Limit: db.MaxPageSize + 1},
+		"malformed cursor":     {Cursor: "not a cursor"},
+		"cursor of other sort": {Sort: "author", Cursor: page.NextCursor},
+	}
+
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			_, err := bs.Query(context.Background(), query)
+
+			// Assert
+			assert.ErrorIs(t, err, db.ErrInvalidQuery)
+		})
+	}
+}
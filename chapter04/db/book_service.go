@@ -1,61 +1,118 @@
 package db
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
 
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
 	"github.com/google/uuid"
 )
 
 type Book struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Author  string `json:"author"`
-	OwnerID string `json:"owner_id"`
-	Status  string `json:"status"`
+	ID        string         `json:"id"`
+	Name      string         `json:"name" validate:"required,max=200"`
+	Author    string         `json:"author" validate:"required,max=100"`
+	OwnerID   string         `json:"owner_id"`
+	Status    BookStatus     `json:"status"`
+	History   []StatusChange `json:"history,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	// DeletedAt is set when the owner deletes the book. Deleted books are
+	// kept so that the swaps they took part in can still be looked up.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
+// Validate returns a *ValidationError listing the invalid fields of the book.
+func (b Book) Validate() error {
+	return validate(b)
+}
+
+// BookService manages books and is safe for concurrent use.
 type BookService struct {
-	books map[string]Book
-	ps    PostingService
+	// mu serialises the read-modify-write operations on books,
+	// so that two swaps of the same book cannot both succeed.
+	mu      sync.Mutex
+	books   BookRepository
+	ps      PostingService
+	outbox  *Outbox
+	logger  *slog.Logger
+	metrics *serviceMetrics
 }
 
+// NewBookService creates a BookService that keeps the initial books
+// and its outbox in memory, logs to the default logger and keeps its
+// metrics in a registry of its own.
 func NewBookService(initial []Book, ps PostingService) *BookService {
-	books := make(map[string]Book)
-	for _, b := range initial {
-		books[b.ID] = b
-	}
-	return &BookService{
-		books: books,
-		ps:    ps,
+	return NewBookServiceWithRepository(NewMemoryBookRepository(initial), NewMemoryOutboxRepository(nil), ps, slog.Default(), metrics.NewRegistry())
+}
+
+// NewBookServiceWithRepository creates a BookService that stores books in repo
+// and the posting orders waiting to be delivered to ps in events. It registers
+// its counters and the number of books by status in reg.
+func NewBookServiceWithRepository(repo BookRepository, events OutboxRepository, ps PostingService, logger *slog.Logger, reg *metrics.Registry) *BookService {
+	bs := &BookService{
+		books:   repo,
+		ps:      ps,
+		logger:  logger,
+		metrics: newServiceMetrics(reg),
 	}
+	bs.outbox = newOutbox(events, repo, &bs.mu, ps, logger, bs.metrics)
+	reg.GaugeFunc("bookswap_books", "Books that are not deleted, by status.", "status", bs.countByStatus)
+	return bs
+}
+
+// Outbox returns the outbox that delivers the posting orders of swapped books.
+func (bs *BookService) Outbox() *Outbox {
+	return bs.outbox
 }
 
-// Get returns a given book or error if none exists.
-func (bs *BookService) Get(id string) (*Book, error) {
-	// To Do: Implement
-	book, ok := bs.books[id]
-	if !ok {
-		return nil, errors.New("no book found")
+// Get returns a given book or error if none exists or it was deleted.
+func (bs *BookService) Get(ctx context.Context, id string) (*Book, error) {
+	book, ok := bs.books.Get(id)
+	if !ok || book.DeletedAt != nil {
+		return nil, NewError(ErrNotFound, "book_not_found", "no book found")
 	}
 	return &book, nil
 }
 
-// Upsert creates or updates a book.
-func (bs *BookService) Upsert(b Book) Book {
-	_, ok := bs.books[b.ID]
-	if !ok {
+// Upsert creates or updates a book. New books are available; the status
+// and history of existing books are kept, as statuses only change through
+// swaps and re-listing. Existing books can only be updated by their owner.
+func (bs *BookService) Upsert(ctx context.Context, b Book) (Book, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	existing, ok := bs.books.Get(b.ID)
+	switch {
+	case !ok:
 		b.ID = uuid.NewString()
-		b.Status = Available.String()
+		b.Status = Available
+		b.History = nil
+		b.CreatedAt = time.Now().UTC()
+		b.DeletedAt = nil
+	case existing.DeletedAt != nil:
+		return Book{}, ErrBookNotFound
+	case existing.OwnerID != b.OwnerID:
+		return Book{}, fmt.Errorf("%w: only the owner can update a book", ErrNotAllowed)
+	default:
+		b.Status = existing.Status
+		b.History = existing.History
+		b.CreatedAt = existing.CreatedAt
+		b.DeletedAt = nil
+	}
+	if err := bs.books.Save(b); err != nil {
+		return Book{}, err
 	}
-	bs.books[b.ID] = b
-	return b
+	bs.metrics.upserts.Inc("book")
+	return b, nil
 }
 
 // List returns the list of available books.
-func (bs *BookService) List() []Book {
+func (bs *BookService) List(ctx context.Context) []Book {
 	var items []Book = make([]Book, 0)
-	for _, b := range bs.books {
-		if b.Status == Available.String() {
+	for _, b := range bs.books.List() {
+		if b.Status == Available && b.DeletedAt == nil {
 			items = append(items, b)
 		}
 	}
@@ -63,27 +120,114 @@ func (bs *BookService) List() []Book {
 }
 
 // ListByUser returns the list of books for a given user.
-func (bs *BookService) ListByUser(userID string) []Book {
+func (bs *BookService) ListByUser(ctx context.Context, userID string) []Book {
 	var items = make([]Book, 0)
-	for _, b := range bs.books {
-		if b.OwnerID == userID {
+	for _, b := range bs.books.List() {
+		if b.OwnerID == userID && b.DeletedAt == nil {
 			items = append(items, b)
 		}
 	}
 	return items
 }
 
-// SwapBook checks whether a book is available and, if possible, marks it as swapped.
-func (bs *BookService) SwapBook(bookID, userID string) (*Book, error) {
-	book, ok := bs.books[bookID]
-	if !ok {
-		return nil, errors.New("book doesn't exist")
+// SwapBook checks whether a book is available and, if possible, marks it as swapped
+// and records a posting order for it in the outbox. Owners cannot swap their own books.
+func (bs *BookService) SwapBook(ctx context.Context, bookID, userID string) (*Book, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	book, ok := bs.books.Get(bookID)
+	if !ok || book.DeletedAt != nil {
+		return nil, ErrBookNotFound
 	}
-	if book.Status == Swapped.String() {
-		return nil, errors.New("book is not available")
+	if book.OwnerID == userID {
+		return nil, fmt.Errorf("%w: cannot swap your own book", ErrNotAllowed)
+	}
+	if book.Status != Available {
+		return nil, NewError(ErrConflict, "book_not_available", "book is not available")
+	}
+	if err := setStatus(&book, Swapped, userID); err != nil {
+		return nil, err
+	}
+	if err := bs.outbox.enqueue(ctx, book, ""); err != nil {
+		return nil, err
 	}
-	book.Status = Swapped.String()
 	book.OwnerID = userID
-	bs.books[bookID] = book
+	if err := bs.books.Save(book); err != nil {
+		return nil, err
+	}
+	bs.logger.InfoContext(ctx, "book swapped", "book_id", book.ID, "user_id", userID)
+	bs.metrics.swaps.Inc("swap")
+	return &book, nil
+}
+
+// Relist makes a book that its owner has received through a swap available again.
+// Books with an open swap only become available when the swap is rejected or cancelled.
+func (bs *BookService) Relist(ctx context.Context, bookID, userID string) (*Book, error) {
+	return bs.update(bookID, func(b *Book) error {
+		if b.OwnerID != userID {
+			return fmt.Errorf("%w: only the owner can re-list a book", ErrNotAllowed)
+		}
+		if b.Status != Swapped && b.Status != Received {
+			return fmt.Errorf("%w: cannot re-list %s book", ErrInvalidTransition, b.Status)
+		}
+		return setStatus(b, Available, userID)
+	})
+}
+
+// Update atomically applies change to a book owned by userID. Only the name
+// and author are taken from the changed book; the ID, owner, status and
+// history of a book only change through swaps.
+func (bs *BookService) Update(ctx context.Context, bookID, userID string, change func(b *Book) error) (*Book, error) {
+	return bs.update(bookID, func(b *Book) error {
+		if b.OwnerID != userID {
+			return fmt.Errorf("%w: only the owner can update a book", ErrNotAllowed)
+		}
+		edited := *b
+		if err := change(&edited); err != nil {
+			return err
+		}
+		b.Name, b.Author = edited.Name, edited.Author
+		return nil
+	})
+}
+
+// Delete soft deletes a book owned by userID. The book is hidden from lists
+// and queries, but kept for the history of its swaps. Books cannot be deleted
+// while they are being swapped.
+func (bs *BookService) Delete(ctx context.Context, bookID, userID string) error {
+	_, err := bs.update(bookID, func(b *Book) error {
+		if b.OwnerID != userID {
+			return fmt.Errorf("%w: only the owner can delete a book", ErrNotAllowed)
+		}
+		switch b.Status {
+		case Requested, Accepted, Shipped:
+			return fmt.Errorf("%w: cannot delete a book that is being swapped", ErrInvalidTransition)
+		}
+		now := time.Now().UTC()
+		b.DeletedAt = &now
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	bs.logger.InfoContext(ctx, "book deleted", "book_id", bookID, "user_id", userID)
+	return nil
+}
+
+// update atomically applies change to a book and saves the result.
+// The book is left untouched if change returns an error.
+func (bs *BookService) update(bookID string, change func(b *Book) error) (*Book, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	book, ok := bs.books.Get(bookID)
+	if !ok || book.DeletedAt != nil {
+		return nil, ErrBookNotFound
+	}
+	if err := change(&book); err != nil {
+		return nil, err
+	}
+	if err := bs.books.Save(book); err != nil {
+		return nil, err
+	}
 	return &book, nil
 }
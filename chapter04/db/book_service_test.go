@@ -1,6 +1,7 @@
 package db_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -16,7 +17,7 @@ func TestGet(t *testing.T) {
 		book := db.Book{
 			ID:     uuid.New().String(),
 			Name:   "Existing book",
-			Status: db.Available.String(),
+			Status: db.Available,
 		}
 
 		// The difference between null and undefined in JS is that when you assign null to a variable, it points to void, non-existent location,
@@ -45,7 +46,7 @@ func TestGet(t *testing.T) {
 		for name, tc := range tests {
 			t.Run(name, func(t *testing.T) {
 				// Act
-				book, err := bookService.Get(tc.id)
+				book, err := bookService.Get(context.Background(), tc.id)
 
 				// Assert
 				if tc.wantErr != nil {
@@ -67,10 +68,11 @@ func TestGet(t *testing.T) {
 		bookId := "1122-3322"
 
 		// Act
-		b, err := bs.Get(bookId)
+		b, err := bs.Get(context.Background(), bookId)
 
 		// Assert
-		assert.Equal(t, errors.New("no book found"), err)
+		assert.EqualError(t, err, "no book found")
+		assert.ErrorIs(t, err, db.ErrNotFound)
 		assert.Nil(t, b)
 	})
 }
@@ -81,21 +83,23 @@ func TestUpsert(t *testing.T) {
 		book := db.Book{
 			ID:      uuid.New().String(),
 			Name:    "Existing book",
-			Status:  db.Available.String(),
+			Status:  db.Available,
 			OwnerID: uuid.New().String(),
 		}
 
 		bookService := db.NewBookService([]db.Book{book}, nil)
 		updatedBook := db.Book{
-			ID:     book.ID,
-			Name:   "Updated",
-			Status: book.Status,
+			ID:      book.ID,
+			Name:    "Updated",
+			Status:  book.Status,
+			OwnerID: book.OwnerID,
 		}
 
 		// Act
-		returnedBook := bookService.Upsert(updatedBook)
+		returnedBook, err := bookService.Upsert(context.Background(), updatedBook)
 
 		// Assert
+		require.Nil(t, err)
 		require.NotNil(t, returnedBook)
 		assert.Equal(t, updatedBook, returnedBook)
 	})
@@ -109,14 +113,15 @@ func TestUpsert(t *testing.T) {
 		bookService := db.NewBookService([]db.Book{}, nil)
 
 		// Act
-		returnedBook := bookService.Upsert(book)
+		returnedBook, err := bookService.Upsert(context.Background(), book)
 
 		// Assert
+		require.Nil(t, err)
 		require.NotNil(t, returnedBook)
 		assert.NotEmpty(t, returnedBook.ID)
 		assert.Equal(t, book.Name, returnedBook.Name)
 		assert.Equal(t, book.OwnerID, returnedBook.OwnerID)
-		assert.Equal(t, db.Available.String(), returnedBook.Status)
+		assert.Equal(t, db.Available, returnedBook.Status)
 	})
 }
 
@@ -128,7 +133,7 @@ func TestList(t *testing.T) {
 			Name:    "Book 1",
 			Author:  "Author 1",
 			OwnerID: uuid.New().String(),
-			Status:  db.Available.String(),
+			Status:  db.Available,
 		}
 
 		bookTwo := db.Book{
@@ -136,7 +141,7 @@ func TestList(t *testing.T) {
 			Name:    "Book 2",
 			Author:  "Author 2",
 			OwnerID: uuid.New().String(),
-			Status:  db.Available.String(),
+			Status:  db.Available,
 		}
 
 		bookThree := db.Book{
@@ -144,13 +149,13 @@ func TestList(t *testing.T) {
 			Name:    "Book 3",
 			Author:  "Author 3",
 			OwnerID: uuid.New().String(),
-			Status:  db.Swapped.String(),
+			Status:  db.Swapped,
 		}
 
 		bookService := db.NewBookService([]db.Book{bookOne, bookTwo, bookThree}, nil)
 
 		// Act
-		returnedAvailableBooks := bookService.List()
+		returnedAvailableBooks := bookService.List(context.Background())
 
 		// Assert
 		assert.NotEmpty(t, returnedAvailableBooks)
@@ -164,7 +169,7 @@ func TestList(t *testing.T) {
 		bookService := db.NewBookService([]db.Book{}, nil)
 
 		// Act
-		returnedAvailableBooks := bookService.List()
+		returnedAvailableBooks := bookService.List(context.Background())
 
 		assert.NotNil(t, returnedAvailableBooks)
 		assert.Len(t, returnedAvailableBooks, 0)
@@ -180,27 +185,27 @@ func TestListByUser(t *testing.T) {
 			Name:    "Book One",
 			Author:  "Author One",
 			OwnerID: ownerId,
-			Status:  db.Available.String(),
+			Status:  db.Available,
 		}
 		bookTwo := db.Book{
 			ID:      uuid.New().String(),
 			Name:    "Book Two",
 			Author:  "Author Two",
 			OwnerID: ownerId,
-			Status:  db.Available.String(),
+			Status:  db.Available,
 		}
 		bookThree := db.Book{
 			ID:      uuid.New().String(),
 			Name:    "Book Three",
 			Author:  "Author Three",
 			OwnerID: uuid.New().String(),
-			Status:  db.Available.String(),
+			Status:  db.Available,
 		}
 
 		bookService := db.NewBookService([]db.Book{bookOne, bookTwo, bookThree}, nil)
 
 		// Act
-		books := bookService.ListByUser(ownerId)
+		books := bookService.ListByUser(context.Background(), ownerId)
 
 		// Assert
 		assert.Len(t, books, 2)
@@ -220,12 +225,12 @@ func TestSwapBook(t *testing.T) {
 			Name:    "Book One",
 			Author:  "Author One",
 			OwnerID: uuid.New().String(),
-			Status:  db.Available.String(),
+			Status:  db.Available,
 		}
 		bookService := db.NewBookService([]db.Book{bookOne}, nil)
 
 		// Act
-		book, error := bookService.SwapBook(bookId, ownerId)
+		book, error := bookService.SwapBook(context.Background(), bookId, ownerId)
 
 		// Assert
 		require.Nil(t, error)
@@ -234,7 +239,7 @@ func TestSwapBook(t *testing.T) {
 		assert.Equal(t, bookOne.ID, book.ID)
 		assert.Equal(t, bookOne.Name, book.Name)
 		assert.Equal(t, ownerId, book.OwnerID)
-		assert.Equal(t, db.Swapped.String(), book.Status)
+		assert.Equal(t, db.Swapped, book.Status)
 	})
 
 	t.Run("book-does-not-exist", func(t *testing.T) {
@@ -244,7 +249,7 @@ func TestSwapBook(t *testing.T) {
 		bookService := db.NewBookService([]db.Book{}, nil)
 
 		// Act
-		book, error := bookService.SwapBook(bookId, ownerId)
+		book, error := bookService.SwapBook(context.Background(), bookId, ownerId)
 
 		// Assert
 		require.Nil(t, book)
@@ -261,16 +266,228 @@ func TestSwapBook(t *testing.T) {
 			Name:    "Book One",
 			Author:  "Author One",
 			OwnerID: uuid.New().String(),
-			Status:  db.Swapped.String(),
+			Status:  db.Swapped,
 		}
 		bookService := db.NewBookService([]db.Book{bookOne}, nil)
 
 		// Act
-		book, error := bookService.SwapBook(bookId, ownerId)
+		book, error := bookService.SwapBook(context.Background(), bookId, ownerId)
 
 		// Assert
 		require.Nil(t, book)
 		require.NotNil(t, error)
 		assert.EqualError(t, error, "book is not available")
 	})
+
+	t.Run("own-book", func(t *testing.T) {
+		// Arrange
+		ownerId := uuid.New().String()
+		bookId := uuid.New().String()
+		bookOne := db.Book{
+			ID:      bookId,
+			Name:    "Book One",
+			Author:  "Author One",
+			OwnerID: ownerId,
+			Status:  db.Available,
+		}
+		bookService := db.NewBookService([]db.Book{bookOne}, nil)
+
+		// Act
+		book, error := bookService.SwapBook(context.Background(), bookId, ownerId)
+
+		// Assert
+		require.Nil(t, book)
+		assert.ErrorIs(t, error, db.ErrNotAllowed)
+		assertBookStatus(t, bookService, bookId, db.Available)
+	})
+}
+
+func TestRelist(t *testing.T) {
+	t.Run("received book", func(t *testing.T) {
+		// Arrange
+		ss, bs, book, requesterID := setupSwap(t)
+		swap, err := ss.Request(context.Background(), book.ID, requesterID)
+		require.Nil(t, err)
+		for _, step := range []func(context.Context, string, string) (*db.Swap, error){ss.Accept, ss.Ship} {
+			_, err = step(context.Background(), swap.ID, book.OwnerID)
+			require.Nil(t, err)
+		}
+		_, err = ss.Receive(context.Background(), swap.ID, requesterID)
+		require.Nil(t, err)
+
+		// Act
+		relisted, err := bs.Relist(context.Background(), book.ID, requesterID)
+
+		// Assert
+		require.Nil(t, err)
+		assert.Equal(t, db.Available, relisted.Status)
+		assert.Equal(t, requesterID, relisted.OwnerID)
+		var statuses []db.BookStatus
+		for _, c := range relisted.History {
+			statuses = append(statuses, c.To)
+		}
+		assert.Equal(t, []db.BookStatus{db.Requested, db.Accepted, db.Shipped, db.Received, db.Available}, statuses)
+		assert.Equal(t, requesterID, relisted.History[4].UserID)
+		assert.Contains(t, bs.List(context.Background()), *relisted)
+	})
+
+	t.Run("not the owner", func(t *testing.T) {
+		// Arrange
+		bookService := db.NewBookService([]db.Book{{ID: "1", OwnerID: "owner", Status: db.Swapped}}, nil)
+
+		// Act
+		book, error := bookService.Relist(context.Background(), "1", "someone-else")
+
+		// Assert
+		assert.Nil(t, book)
+		assert.ErrorIs(t, error, db.ErrNotAllowed)
+	})
+
+	t.Run("already available", func(t *testing.T) {
+		// Arrange
+		bookService := db.NewBookService([]db.Book{{ID: "1", OwnerID: "owner", Status: db.Available}}, nil)
+
+		// Act
+		book, error := bookService.Relist(context.Background(), "1", "owner")
+
+		// Assert
+		assert.Nil(t, book)
+		assert.ErrorIs(t, error, db.ErrInvalidTransition)
+	})
+
+	t.Run("open swap", func(t *testing.T) {
+		for _, accept := range []bool{false, true} {
+			// Arrange
+			ss, bs, book, requesterID := setupSwap(t)
+			swap, err := ss.Request(context.Background(), book.ID, requesterID)
+			require.Nil(t, err)
+			want := db.Requested
+			if accept {
+				_, err = ss.Accept(context.Background(), swap.ID, book.OwnerID)
+				require.Nil(t, err)
+				want = db.Accepted
+			}
+
+			// Act
+			relisted, err := bs.Relist(context.Background(), book.ID, book.OwnerID)
+
+			// Assert
+			assert.Nil(t, relisted)
+			assert.ErrorIs(t, err, db.ErrInvalidTransition)
+			assertBookStatus(t, bs, book.ID, want)
+			_, err = ss.Request(context.Background(), book.ID, uuid.NewString())
+			assert.ErrorIs(t, err, db.ErrInvalidTransition)
+		}
+	})
+}
+
+func TestUpsertKeepsStatus(t *testing.T) {
+	// Arrange
+	book := db.Book{ID: uuid.New().String(), Name: "Swapped book", Status: db.Swapped}
+	bookService := db.NewBookService([]db.Book{book}, nil)
+
+	// Act
+	returnedBook, err := bookService.Upsert(context.Background(), db.Book{ID: book.ID, Name: "Renamed", Status: db.Available})
+
+	// Assert
+	require.Nil(t, err)
+	assert.Equal(t, "Renamed", returnedBook.Name)
+	assert.Equal(t, db.Swapped, returnedBook.Status)
+}
+
+func TestUpsertOtherOwner(t *testing.T) {
+	// Arrange
+	book := db.Book{ID: uuid.New().String(), Name: "Owned book", OwnerID: uuid.New().String()}
+	bookService := db.NewBookService([]db.Book{book}, nil)
+
+	// Act
+	_, err := bookService.Upsert(context.Background(), db.Book{ID: book.ID, Name: "Stolen", OwnerID: uuid.New().String()})
+
+	// Assert
+	assert.ErrorIs(t, err, db.ErrNotAllowed)
+	stored, _ := bookService.Get(context.Background(), book.ID)
+	assert.Equal(t, "Owned book", stored.Name)
+}
+
+func TestUpdateBook(t *testing.T) {
+	ownerID := uuid.New().String()
+	book := db.Book{ID: uuid.New().String(), Name: "Dune", Author: "Herbert", OwnerID: ownerID, Status: db.Swapped}
+
+	t.Run("owner", func(t *testing.T) {
+		// Arrange
+		bookService := db.NewBookService([]db.Book{book}, nil)
+
+		// Act
+		updated, err := bookService.Update(context.Background(), book.ID, ownerID, func(b *db.Book) error {
+			b.Name = "Dune Messiah"
+			b.OwnerID = "someone-else"
+			b.Status = db.Available
+			return nil
+		})
+
+		// Assert
+		require.Nil(t, err)
+		assert.Equal(t, book.ID, updated.ID)
+		assert.Equal(t, "Dune Messiah", updated.Name)
+		assert.Equal(t, "Herbert", updated.Author)
+		assert.Equal(t, ownerID, updated.OwnerID)
+		assert.Equal(t, db.Swapped, updated.Status)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		// Arrange
+		bookService := db.NewBookService([]db.Book{book}, nil)
+
+		// Act
+		_, err := bookService.Update(context.Background(), book.ID, uuid.New().String(), func(b *db.Book) error {
+			b.Name = "Stolen"
+			return nil
+		})
+
+		// Assert
+		assert.ErrorIs(t, err, db.ErrNotAllowed)
+	})
+}
+
+func TestDeleteBook(t *testing.T) {
+	ownerID := uuid.New().String()
+
+	tests := map[string]struct {
+		status  db.BookStatus
+		userID  string
+		wantErr error
+	}{
+		"available":     {status: db.Available, userID: ownerID},
+		"received":      {status: db.Received, userID: ownerID},
+		"other user":    {status: db.Available, userID: uuid.New().String(), wantErr: db.ErrNotAllowed},
+		"being swapped": {status: db.Accepted, userID: ownerID, wantErr: db.ErrInvalidTransition},
+		"being shipped": {status: db.Shipped, userID: ownerID, wantErr: db.ErrInvalidTransition},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			book := db.Book{ID: uuid.New().String(), Name: "Deleted", OwnerID: ownerID, Status: tc.status}
+			bookService := db.NewBookService([]db.Book{book}, nil)
+
+			// Act
+			err := bookService.Delete(context.Background(), book.ID, tc.userID)
+
+			// Assert
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				_, err = bookService.Get(context.Background(), book.ID)
+				assert.Nil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			_, err = bookService.Get(context.Background(), book.ID)
+			assert.NotNil(t, err)
+			assert.Empty(t, bookService.List(context.Background()))
+			assert.Empty(t, bookService.ListByUser(context.Background(), ownerID))
+			_, err = bookService.SwapBook(context.Background(), book.ID, uuid.New().String())
+			assert.ErrorIs(t, err, db.ErrBookNotFound)
+			assert.ErrorIs(t, bookService.Delete(context.Background(), book.ID, ownerID), db.ErrBookNotFound)
+		})
+	}
 }
@@ -1,13 +1,99 @@
 package db
 
+import (
+	"fmt"
+	"time"
+)
+
 // BooksStatus contains the different types of Book status.
 type BookStatus int
 
 const (
 	Available BookStatus = iota
 	Swapped
+	Requested
+	Accepted
+	Shipped
+	Received
 )
 
+var bookStatusNames = [...]string{"AVAILABLE", "SWAPPED", "REQUESTED", "ACCEPTED", "SHIPPED", "RECEIVED"}
+
+// transitions lists the statuses a book may move to from each status.
+var transitions = map[BookStatus][]BookStatus{
+	Available: {Swapped, Requested},
+	Swapped:   {Available},
+	Requested: {Accepted, Available},
+	Accepted:  {Shipped, Available},
+	Shipped:   {Received},
+	Received:  {Available},
+}
+
 func (o BookStatus) String() string {
-	return [...]string{"AVAILABLE", "SWAPPED"}[o]
+	if o < 0 || int(o) >= len(bookStatusNames) {
+		return fmt.Sprintf("BookStatus(%d)", int(o))
+	}
+	return bookStatusNames[o]
+}
+
+// ParseBookStatus returns the BookStatus with the given name.
+func ParseBookStatus(name string) (BookStatus, error) {
+	for i, n := range bookStatusNames {
+		if n == name {
+			return BookStatus(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown book status %q", name)
+}
+
+// CanTransitionTo reports whether a book may move from o to the given status.
+func (o BookStatus) CanTransitionTo(to BookStatus) bool {
+	for _, s := range transitions[o] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalText encodes the status by name, so it appears as a string in JSON.
+func (o BookStatus) MarshalText() ([]byte, error) {
+	if o < 0 || int(o) >= len(bookStatusNames) {
+		return nil, fmt.Errorf("unknown book status %d", int(o))
+	}
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText decodes a status name, rejecting unknown statuses.
+func (o *BookStatus) UnmarshalText(text []byte) error {
+	s, err := ParseBookStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*o = s
+	return nil
+}
+
+// StatusChange records a change of the status of a book.
+type StatusChange struct {
+	From   BookStatus `json:"from"`
+	To     BookStatus `json:"to"`
+	UserID string     `json:"user_id,omitempty"`
+	At     time.Time  `json:"at"`
+}
+
+// setStatus moves b to the given status on behalf of userID and records
+// the change in its history, provided the transition table allows it.
+func setStatus(b *Book, to BookStatus, userID string) error {
+	if !b.Status.CanTransitionTo(to) {
+		return fmt.Errorf("%w: cannot move book from %s to %s", ErrInvalidTransition, b.Status, to)
+	}
+	b.History = append(b.History, StatusChange{
+		From:   b.Status,
+		To:     to,
+		UserID: userID,
+		At:     time.Now().UTC(),
+	})
+	b.Status = to
+	return nil
 }
@@ -0,0 +1,80 @@
+package db_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookStatusString(t *testing.T) {
+	assert.Equal(t, "RECEIVED", db.Received.String())
+	assert.Equal(t, "BookStatus(42)", db.BookStatus(42).String())
+}
+
+func TestParseBookStatus(t *testing.T) {
+	t.Run("known status", func(t *testing.T) {
+		status, error := db.ParseBookStatus("SHIPPED")
+		require.Nil(t, error)
+		assert.Equal(t, db.Shipped, status)
+	})
+
+	t.Run("unknown status", func(t *testing.T) {
+		_, error := db.ParseBookStatus("LOST")
+		assert.EqualError(t, error, `unknown book status "LOST"`)
+	})
+}
+
+func TestBookStatusJSON(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		// Arrange
+		book := db.Book{ID: "1", Status: db.Swapped}
+
+		// Act
+		data, error := json.Marshal(book)
+		require.Nil(t, error)
+		var decoded db.Book
+		error = json.Unmarshal(data, &decoded)
+
+		// Assert
+		require.Nil(t, error)
+		assert.Contains(t, string(data), `"status":"SWAPPED"`)
+		assert.Equal(t, book, decoded)
+	})
+
+	t.Run("unknown status", func(t *testing.T) {
+		var book db.Book
+		error := json.Unmarshal([]byte(`{"status":"LOST"}`), &book)
+		assert.ErrorContains(t, error, `unknown book status "LOST"`)
+	})
+
+	t.Run("invalid status value", func(t *testing.T) {
+		_, error := json.Marshal(db.Book{Status: db.BookStatus(42)})
+		assert.ErrorContains(t, error, "unknown book status 42")
+	})
+}
+
+func TestCanTransitionTo(t *testing.T) {
+	tests := map[string]struct {
+		from, to db.BookStatus
+		want     bool
+	}{
+		"request available":   {from: db.Available, to: db.Requested, want: true},
+		"swap available":      {from: db.Available, to: db.Swapped, want: true},
+		"relist swapped":      {from: db.Swapped, to: db.Available, want: true},
+		"relist received":     {from: db.Received, to: db.Available, want: true},
+		"reject requested":    {from: db.Requested, to: db.Available, want: true},
+		"ship requested":      {from: db.Requested, to: db.Shipped, want: false},
+		"cancel shipped":      {from: db.Shipped, to: db.Available, want: false},
+		"receive available":   {from: db.Available, to: db.Received, want: false},
+		"unknown from status": {from: db.BookStatus(42), to: db.Available, want: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.from.CanTransitionTo(tc.to))
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package db
+
+import "errors"
+
+// The kinds of the errors of the services. Every error that the services
+// return on purpose is one of these kinds, which callers check with errors.Is,
+// while other errors are internal failures.
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrInvalid         = errors.New("invalid")
+	ErrForbidden       = errors.New("forbidden")
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrUnavailable is the kind of the failures of the services that the
+	// services depend on, such as the posting service.
+	ErrUnavailable = errors.New("unavailable")
+)
+
+// Error is an error of a given kind with a machine readable code,
+// such as book_not_found.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+}
+
+// NewError returns an error of the given kind, one of ErrNotFound, ErrConflict,
+// ErrInvalid, ErrForbidden, ErrUnauthenticated and ErrUnavailable.
+func NewError(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether e is of the kind target.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// ErrorCode returns the code of err, internal_error for errors without one.
+func ErrorCode(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return "validation_failed"
+	}
+	return "internal_error"
+}
@@ -0,0 +1,66 @@
+package db_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorKinds(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		wantKind error
+		wantCode string
+	}{
+		"book not found": {
+			err:      db.ErrBookNotFound,
+			wantKind: db.ErrNotFound,
+			wantCode: "book_not_found",
+		},
+		"wrapped not allowed": {
+			err:      fmt.Errorf("%w: cannot request your own book", db.ErrNotAllowed),
+			wantKind: db.ErrForbidden,
+			wantCode: "not_allowed",
+		},
+		"invalid transition": {
+			err:      db.ErrInvalidTransition,
+			wantKind: db.ErrConflict,
+			wantCode: "invalid_transition",
+		},
+		"validation": {
+			err:      db.Book{}.Validate(),
+			wantKind: db.ErrInvalid,
+			wantCode: "validation_failed",
+		},
+		"invalid token": {
+			err:      db.ErrInvalidToken,
+			wantKind: db.ErrUnauthenticated,
+			wantCode: "invalid_token",
+		},
+		"posting failed": {
+			err:      fmt.Errorf("%w: status 503", db.ErrPostingFailed),
+			wantKind: db.ErrUnavailable,
+			wantCode: "posting_failed",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Assert
+			assert.ErrorIs(t, tc.err, tc.wantKind)
+			assert.Equal(t, tc.wantCode, db.ErrorCode(tc.err))
+		})
+	}
+}
+
+func TestErrorCodeOfInternalError(t *testing.T) {
+	// Act
+	code := db.ErrorCode(errors.New("disk full"))
+
+	// Assert
+	assert.Equal(t, "internal_error", code)
+	assert.NotErrorIs(t, errors.New("disk full"), db.ErrNotFound)
+}
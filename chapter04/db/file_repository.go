@@ -0,0 +1,287 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// compactThreshold is the number of log entries after which the log is
+// compacted into a snapshot, provided it holds at least twice as many
+// entries as there are records.
+const compactThreshold = 1000
+
+// FileBookRepository is a BookRepository persisted to an append-only log.
+type FileBookRepository struct {
+	*fileStore[Book]
+}
+
+// NewFileBookRepository opens the book log at path, creating it with the
+// initial books if it does not exist yet.
+func NewFileBookRepository(path string, initial []Book) (*FileBookRepository, error) {
+	fs, err := openFileStore(path, initial, bookID)
+	if err != nil {
+		return nil, err
+	}
+	return &FileBookRepository{fs}, nil
+}
+
+// FileUserRepository is a UserRepository persisted to an append-only log.
+type FileUserRepository struct {
+	*fileStore[User]
+}
+
+// NewFileUserRepository opens the user log at path, creating it with the
+// initial users if it does not exist yet.
+func NewFileUserRepository(path string, initial []User) (*FileUserRepository, error) {
+	fs, err := openFileStore(path, initial, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &FileUserRepository{fs}, nil
+}
+
+// FileSwapRepository is a SwapRepository persisted to an append-only log.
+type FileSwapRepository struct {
+	*fileStore[Swap]
+}
+
+// NewFileSwapRepository opens the swap log at path, creating it if it does not exist yet.
+func NewFileSwapRepository(path string) (*FileSwapRepository, error) {
+	fs, err := openFileStore(path, nil, swapID)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSwapRepository{fs}, nil
+}
+
+// FileOutboxRepository is an OutboxRepository persisted to an append-only log.
+type FileOutboxRepository struct {
+	*fileStore[OutboxEvent]
+}
+
+// NewFileOutboxRepository opens the outbox log at path, creating it if it does not exist yet.
+func NewFileOutboxRepository(path string) (*FileOutboxRepository, error) {
+	fs, err := openFileStore(path, nil, eventID)
+	if err != nil {
+		return nil, err
+	}
+	return &FileOutboxRepository{fs}, nil
+}
+
+// FileCredentialRepository is a CredentialRepository persisted to an append-only log.
+type FileCredentialRepository struct {
+	*fileStore[Credential]
+}
+
+// NewFileCredentialRepository opens the credential log at path, creating it if it does not exist yet.
+func NewFileCredentialRepository(path string) (*FileCredentialRepository, error) {
+	fs, err := openFileStore(path, nil, credentialID)
+	if err != nil {
+		return nil, err
+	}
+	return &FileCredentialRepository{fs}, nil
+}
+
+// fileStore keeps its records in memory and appends every save to a log
+// of JSON lines, one record per line, that is replayed when it is opened.
+// The latest line of a record wins. A partial last line, left behind by
+// a crash during a write, is discarded.
+type fileStore[T any] struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	entries int
+	// retryAt is the number of entries before which a failed compaction
+	// is not tried again.
+	retryAt int
+	*memoryStore[T]
+}
+
+func openFileStore[T any](path string, initial []T, id func(T) string) (*fileStore[T], error) {
+	fs := &fileStore[T]{
+		path:        path,
+		memoryStore: newMemoryStore(nil, id),
+	}
+
+	_, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		for _, r := range initial {
+			fs.records[id(r)] = r
+		}
+		if err := fs.snapshot(); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if err := fs.replay(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := fs.open(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// replay loads the records of the log.
+func (fs *fileStore[T]) replay() error {
+	data, err := os.ReadFile(fs.path)
+	if err != nil {
+		return err
+	}
+
+	valid := 0
+	r := bufio.NewReader(bytes.NewReader(data))
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Anything after the last newline is a partial write.
+			break
+		}
+		var rec T
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("corrupt log %s at byte %d: %v", fs.path, valid, err)
+		}
+		fs.records[fs.id(rec)] = rec
+		fs.entries++
+		valid += len(line)
+	}
+
+	if valid < len(data) {
+		return os.Truncate(fs.path, int64(valid))
+	}
+	return nil
+}
+
+// Save appends r to the log and syncs it to disk before updating memory.
+// Compaction failures are logged rather than returned, as r is saved by then.
+func (fs *fileStore[T]) Save(r T) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := fs.open(); err != nil {
+		return err
+	}
+	if _, err := fs.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := fs.file.Sync(); err != nil {
+		return err
+	}
+	fs.memoryStore.Save(r)
+	fs.entries++
+
+	if fs.entries >= compactThreshold && fs.entries >= fs.retryAt && fs.entries >= 2*fs.len() {
+		if err := fs.compact(); err != nil {
+			fs.retryAt = fs.entries + compactThreshold
+			slog.Warn("cannot compact log", "path", fs.path, "error", err)
+		}
+	}
+	return nil
+}
+
+// compact replaces the log with a snapshot of the current records.
+// The log stays open until the snapshot has replaced it, so that a failed
+// compaction leaves the log as it was.
+func (fs *fileStore[T]) compact() error {
+	if err := fs.snapshot(); err != nil {
+		return err
+	}
+	// The open log has been replaced by the snapshot, so it is closed even
+	// if the snapshot cannot be opened; the next save opens it again.
+	fs.file.Close()
+	fs.file = nil
+	return fs.open()
+}
+
+// open opens the log for appending, unless it is open already.
+func (fs *fileStore[T]) open() error {
+	if fs.file != nil {
+		return nil
+	}
+	f, err := os.OpenFile(fs.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	fs.file = f
+	return nil
+}
+
+// snapshot atomically writes every record to the log, one line each,
+// through a temporary file that is renamed over the log.
+func (fs *fileStore[T]) snapshot() error {
+	tmp, err := os.CreateTemp(filepath.Dir(fs.path), ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	records := fs.List()
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), fs.path); err != nil {
+		return err
+	}
+	fs.entries = len(records)
+	return nil
+}
+
+// Check returns an error if the log has been closed or removed.
+func (fs *fileStore[T]) Check() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.open(); err != nil {
+		return err
+	}
+	if _, err := fs.file.Stat(); err != nil {
+		return err
+	}
+	_, err := os.Stat(fs.path)
+	return err
+}
+
+// Close syncs and closes the log. The repository must not be used afterwards.
+func (fs *fileStore[T]) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.file == nil {
+		return nil
+	}
+	if err := fs.file.Sync(); err != nil {
+		fs.file.Close()
+		return err
+	}
+	return fs.file.Close()
+}
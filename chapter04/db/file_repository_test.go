@@ -0,0 +1,170 @@
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileBookRepository(t *testing.T) {
+	t.Run("seeds a new log", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "books.log")
+		book := db.Book{ID: uuid.NewString(), Name: "Seeded", Status: db.Available}
+
+		// Act
+		repo, error := db.NewFileBookRepository(path, []db.Book{book})
+
+		// Assert
+		require.Nil(t, error)
+		defer repo.Close()
+		got, ok := repo.Get(book.ID)
+		require.True(t, ok)
+		assert.Equal(t, book, got)
+	})
+
+	t.Run("keeps saves across reopening", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "books.log")
+		seeded := db.Book{ID: uuid.NewString(), Name: "Seeded", Status: db.Available}
+		repo, error := db.NewFileBookRepository(path, []db.Book{seeded})
+		require.Nil(t, error)
+		bs := db.NewBookServiceWithRepository(repo, db.NewMemoryOutboxRepository(nil), nil, slog.Default(), metrics.NewRegistry())
+		created, error := bs.Upsert(context.Background(), db.Book{Name: "Created", OwnerID: uuid.NewString()})
+		require.Nil(t, error)
+		swapped, error := bs.SwapBook(context.Background(), seeded.ID, created.OwnerID)
+		require.Nil(t, error)
+		require.Nil(t, repo.Close())
+
+		// Act
+		reopened, error := db.NewFileBookRepository(path, nil)
+
+		// Assert
+		require.Nil(t, error)
+		defer reopened.Close()
+		assert.Len(t, reopened.List(), 2)
+		got, ok := reopened.Get(seeded.ID)
+		require.True(t, ok)
+		assert.Equal(t, *swapped, got)
+		got, ok = reopened.Get(created.ID)
+		require.True(t, ok)
+		assert.Equal(t, created, got)
+	})
+
+	t.Run("discards a partial last line", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "books.log")
+		book := db.Book{ID: uuid.NewString(), Name: "Complete"}
+		repo, error := db.NewFileBookRepository(path, []db.Book{book})
+		require.Nil(t, error)
+		require.Nil(t, repo.Close())
+		f, error := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+		require.Nil(t, error)
+		_, error = f.WriteString(`{"id":"partial","na`)
+		require.Nil(t, error)
+		require.Nil(t, f.Close())
+
+		// Act
+		reopened, error := db.NewFileBookRepository(path, nil)
+
+		// Assert
+		require.Nil(t, error)
+		defer reopened.Close()
+		assert.Equal(t, []db.Book{book}, reopened.List())
+		require.Nil(t, reopened.Save(db.Book{ID: "next"}))
+		_, ok := reopened.Get("next")
+		assert.True(t, ok)
+	})
+
+	t.Run("corrupt log", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "books.log")
+		require.Nil(t, os.WriteFile(path, []byte("not json\n"), 0o644))
+
+		// Act
+		repo, error := db.NewFileBookRepository(path, nil)
+
+		// Assert
+		assert.Nil(t, repo)
+		assert.ErrorContains(t, error, "corrupt log")
+	})
+
+	t.Run("compacts the log", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "books.log")
+		repo, error := db.NewFileBookRepository(path, nil)
+		require.Nil(t, error)
+		defer repo.Close()
+		book := db.Book{ID: uuid.NewString()}
+
+		// Act
+		for i := 0; i < 1500; i++ {
+			book.Name = uuid.NewString()
+			require.Nil(t, repo.Save(book))
+		}
+
+		// Assert
+		data, error := os.ReadFile(path)
+		require.Nil(t, error)
+		assert.Equal(t, 501, bytes.Count(data, []byte("\n")))
+		got, ok := repo.Get(book.ID)
+		require.True(t, ok)
+		assert.Equal(t, book, got)
+	})
+
+	t.Run("keeps saving when compaction fails", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "books.log")
+		repo, error := db.NewFileBookRepository(path, nil)
+		require.Nil(t, error)
+		defer repo.Close()
+		// A directory in place of the log cannot be replaced by a snapshot.
+		require.Nil(t, os.Remove(path))
+		require.Nil(t, os.MkdirAll(filepath.Join(path, "blocked"), 0o755))
+		book := db.Book{ID: uuid.NewString()}
+
+		// Act
+		for i := 0; i < 1500; i++ {
+			book.Name = uuid.NewString()
+			require.Nil(t, repo.Save(book))
+		}
+
+		// Assert
+		got, ok := repo.Get(book.ID)
+		require.True(t, ok)
+		assert.Equal(t, book, got)
+		entries, error := os.ReadDir(filepath.Dir(path))
+		require.Nil(t, error)
+		assert.Len(t, entries, 1, "snapshots are cleaned up")
+	})
+}
+
+func TestFileUserRepository(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "users.log")
+	repo, error := db.NewFileUserRepository(path, nil)
+	require.Nil(t, error)
+	us := db.NewUserServiceWithRepository(repo, nil, slog.Default(), metrics.NewRegistry())
+	user, error := us.Upsert(context.Background(), db.User{Name: "Persisted", Country: "Florida, US"})
+	require.Nil(t, error)
+	require.Nil(t, repo.Close())
+
+	// Act
+	reopened, error := db.NewFileUserRepository(path, nil)
+
+	// Assert
+	require.Nil(t, error)
+	defer reopened.Close()
+	got, ok := reopened.Get(user.ID)
+	require.True(t, ok)
+	assert.Equal(t, user, got)
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"fmt"
+)
+
+var (
+	ErrStorageUnavailable    = NewError(ErrUnavailable, "storage_unavailable", "storage is unavailable")
+	ErrDispatcherUnavailable = NewError(ErrUnavailable, "dispatcher_unavailable", "posting dispatcher is unavailable")
+)
+
+// healthChecker is implemented by the repositories whose storage can fail,
+// such as the file repositories.
+type healthChecker interface {
+	Check() error
+}
+
+// checkStorage returns an error if any of the repositories that can check
+// their storage reports a failure.
+func checkStorage(repos ...any) error {
+	for _, r := range repos {
+		if hc, ok := r.(healthChecker); ok {
+			if err := hc.Check(); err != nil {
+				return fmt.Errorf("%w: %v", ErrStorageUnavailable, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Check returns an error if the storage of books or of the outbox is unusable.
+func (bs *BookService) Check() error {
+	return checkStorage(bs.books, bs.outbox.events)
+}
+
+// Check returns an error if the storage of users is unusable.
+func (us *UserService) Check() error {
+	return checkStorage(us.users)
+}
+
+// Check returns an error if the storage of swaps is unusable.
+func (ss *SwapService) Check() error {
+	return checkStorage(ss.swaps)
+}
+
+// Check returns an error if the storage of credentials is unusable.
+func (as *AuthService) Check() error {
+	return checkStorage(as.credentials)
+}
@@ -0,0 +1,83 @@
+package db_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageCheck(t *testing.T) {
+	tests := map[string]struct {
+		fail    func(t *testing.T, path string, repo *db.FileBookRepository)
+		wantErr error
+	}{
+		"usable log": {
+			fail: func(*testing.T, string, *db.FileBookRepository) {},
+		},
+		"closed log": {
+			fail: func(t *testing.T, _ string, repo *db.FileBookRepository) {
+				require.Nil(t, repo.Close())
+			},
+			wantErr: db.ErrStorageUnavailable,
+		},
+		"removed log": {
+			fail: func(t *testing.T, path string, _ *db.FileBookRepository) {
+				require.Nil(t, os.Remove(path))
+			},
+			wantErr: db.ErrStorageUnavailable,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			path := filepath.Join(t.TempDir(), "books.log")
+			repo, err := db.NewFileBookRepository(path, nil)
+			require.Nil(t, err)
+			t.Cleanup(func() { repo.Close() })
+			bs := db.NewBookServiceWithRepository(repo, db.NewMemoryOutboxRepository(nil), nil, slog.Default(), metrics.NewRegistry())
+			tc.fail(t, path, repo)
+
+			// Act
+			err = bs.Check()
+
+			// Assert
+			if tc.wantErr == nil {
+				assert.Nil(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.ErrorIs(t, err, db.ErrUnavailable)
+		})
+	}
+}
+
+func TestDispatcherCheck(t *testing.T) {
+	// Arrange
+	bs := db.NewBookService(nil, nil)
+	require.ErrorIs(t, bs.Outbox().Check(), db.ErrDispatcherUnavailable)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// Act
+	go func() {
+		bs.Outbox().Run(ctx, time.Hour)
+		close(done)
+	}()
+
+	// Assert
+	require.Eventually(t, func() bool {
+		return bs.Outbox().Check() == nil
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+	assert.ErrorIs(t, bs.Outbox().Check(), db.ErrDispatcherUnavailable)
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PostingConfig configures an HTTPPostingService.
+type PostingConfig struct {
+	// BaseURL is the address of the posting endpoint, such as https://posting.example.com.
+	BaseURL string
+	// Timeout bounds each attempt of a call. Defaults to 10 seconds.
+	Timeout time.Duration
+	// MaxRetries is the number of times a failed call is retried.
+	MaxRetries int
+	// Backoff is the wait before the first retry, doubled for every further retry.
+	// Defaults to 100 milliseconds.
+	Backoff time.Duration
+	// Logger logs failed attempts. Defaults to the default logger.
+	Logger *slog.Logger
+}
+
+// HTTPPostingService is a PostingService backed by an HTTP posting endpoint.
+// Orders are created with POST {BaseURL}/orders and tracked with
+// GET {BaseURL}/orders/{id}, both answering with an Order as JSON.
+// Network errors, 429 and 5xx responses are retried with exponential backoff.
+// Every attempt to create an order carries the Idempotency-Key header, the key
+// of the context or else a key of the call, so that the endpoint creates the
+// order only once across retries and across calls with the same key.
+type HTTPPostingService struct {
+	cfg    PostingConfig
+	client *http.Client
+}
+
+// NewHTTPPostingService initialises a PostingService that calls the configured endpoint.
+func NewHTTPPostingService(cfg PostingConfig) *HTTPPostingService {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Backoff == 0 {
+		cfg.Backoff = 100 * time.Millisecond
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &HTTPPostingService{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// orderRequest is the body of POST /orders.
+type orderRequest struct {
+	BookID  string `json:"book_id"`
+	Name    string `json:"name"`
+	Author  string `json:"author"`
+	OwnerID string `json:"owner_id"`
+}
+
+// NewOrder creates a posting order for a book, under the idempotency key of ctx if any.
+func (hps *HTTPPostingService) NewOrder(ctx context.Context, b Book) (*Order, error) {
+	body, err := json.Marshal(orderRequest{
+		BookID:  b.ID,
+		Name:    b.Name,
+		Author:  b.Author,
+		OwnerID: b.OwnerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	key := IdempotencyKey(ctx)
+	if key == "" {
+		key = uuid.NewString()
+	}
+	return hps.call(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hps.cfg.BaseURL+"/orders", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", key)
+		return req, nil
+	})
+}
+
+// Track returns the latest status of an order.
+func (hps *HTTPPostingService) Track(ctx context.Context, orderID string) (*Order, error) {
+	return hps.call(ctx, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, hps.cfg.BaseURL+"/orders/"+url.PathEscape(orderID), nil)
+	})
+}
+
+// call sends the request built by newRequest, retrying failed attempts.
+func (hps *HTTPPostingService) call(ctx context.Context, newRequest func() (*http.Request, error)) (*Order, error) {
+	var lastErr error
+	backoff := hps.cfg.Backoff
+	for attempt := 0; attempt <= hps.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, backoff); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrPostingFailed, err)
+			}
+			backoff *= 2
+		}
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		order, err := hps.do(req)
+		if err == nil {
+			return order, nil
+		}
+		lastErr = err
+		hps.cfg.Logger.WarnContext(ctx, "posting attempt failed", "method", req.Method, "path", req.URL.Path, "attempt", attempt+1, "error", err)
+		var retry *retryableError
+		if !errors.As(err, &retry) {
+			break
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", ErrPostingFailed, lastErr)
+}
+
+// sleep waits for d, or returns the error of ctx once it is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryableError is a failure of an attempt that may succeed when retried.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+// do sends a single request and decodes the order it answers with.
+func (hps *HTTPPostingService) do(req *http.Request) (*Order, error) {
+	resp, err := hps.client.Do(req)
+	if err != nil {
+		return nil, &retryableError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return nil, &retryableError{fmt.Errorf("%s %s returned %s", req.Method, req.URL.Path, resp.Status)}
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s %s returned %s: %s", req.Method, req.URL.Path, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var order Order
+	if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+		return nil, fmt.Errorf("invalid order response: %v", err)
+	}
+	if order.ID == "" {
+		return nil, errors.New("invalid order response: missing id")
+	}
+	return &order, nil
+}
@@ -0,0 +1,184 @@
+package db_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postingStandIn is an httptest posting endpoint that fails the first
+// failures calls with the given status before answering with an order.
+type postingStandIn struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	calls    int
+	keys     []string
+}
+
+func (p *postingStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	p.keys = append(p.keys, r.Header.Get("Idempotency-Key"))
+	if p.calls <= p.failures {
+		w.WriteHeader(p.status)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/orders":
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["book_id"] == "" {
+			http.Error(w, "missing book", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(db.Order{ID: "order-" + body["book_id"], Carrier: "standin", Status: db.OrderCreated})
+	case r.Method == http.MethodGet && r.URL.Path == "/orders/order-1":
+		json.NewEncoder(w).Encode(db.Order{ID: "order-1", Carrier: "standin", Status: "IN_TRANSIT"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newPostingService(t *testing.T, standIn *postingStandIn, retries int) *db.HTTPPostingService {
+	t.Helper()
+	srv := httptest.NewServer(standIn)
+	t.Cleanup(srv.Close)
+	return db.NewHTTPPostingService(db.PostingConfig{
+		BaseURL:    srv.URL,
+		MaxRetries: retries,
+		Backoff:    time.Millisecond,
+	})
+}
+
+func TestHTTPPostingServiceNewOrder(t *testing.T) {
+	t.Run("creates order", func(t *testing.T) {
+		// Arrange
+		standIn := &postingStandIn{}
+		ps := newPostingService(t, standIn, 0)
+
+		// Act
+		order, error := ps.NewOrder(context.Background(), db.Book{ID: "1", Name: "Posted"})
+
+		// Assert
+		require.Nil(t, error)
+		assert.Equal(t, &db.Order{ID: "order-1", Carrier: "standin", Status: db.OrderCreated}, order)
+	})
+
+	t.Run("retries server errors with the same idempotency key", func(t *testing.T) {
+		// Arrange
+		standIn := &postingStandIn{failures: 2, status: http.StatusServiceUnavailable}
+		ps := newPostingService(t, standIn, 3)
+
+		// Act
+		order, error := ps.NewOrder(context.Background(), db.Book{ID: "1"})
+
+		// Assert
+		require.Nil(t, error)
+		assert.Equal(t, "order-1", order.ID)
+		assert.Equal(t, 3, standIn.calls)
+		require.Len(t, standIn.keys, 3)
+		assert.NotEmpty(t, standIn.keys[0])
+		assert.Equal(t, standIn.keys[0], standIn.keys[2])
+	})
+
+	t.Run("creates order under the idempotency key of the context", func(t *testing.T) {
+		// Arrange
+		standIn := &postingStandIn{}
+		ps := newPostingService(t, standIn, 0)
+		ctx := db.WithIdempotencyKey(context.Background(), "event-1")
+
+		// Act
+		_, error := ps.NewOrder(ctx, db.Book{ID: "1"})
+		require.Nil(t, error)
+		_, error = ps.NewOrder(ctx, db.Book{ID: "1"})
+
+		// Assert
+		require.Nil(t, error)
+		assert.Equal(t, []string{"event-1", "event-1"}, standIn.keys)
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		// Arrange
+		standIn := &postingStandIn{failures: 10, status: http.StatusTooManyRequests}
+		ps := newPostingService(t, standIn, 2)
+
+		// Act
+		order, error := ps.NewOrder(context.Background(), db.Book{ID: "1"})
+
+		// Assert
+		assert.Nil(t, order)
+		assert.ErrorIs(t, error, db.ErrPostingFailed)
+		assert.Equal(t, 3, standIn.calls)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		// Arrange
+		standIn := &postingStandIn{}
+		ps := newPostingService(t, standIn, 3)
+
+		// Act
+		order, error := ps.NewOrder(context.Background(), db.Book{})
+
+		// Assert
+		assert.Nil(t, order)
+		assert.ErrorIs(t, error, db.ErrPostingFailed)
+		assert.ErrorContains(t, error, "400 Bad Request: missing book")
+		assert.Equal(t, 1, standIn.calls)
+	})
+
+	t.Run("stops retrying once the context is done", func(t *testing.T) {
+		// Arrange
+		standIn := &postingStandIn{failures: 10, status: http.StatusServiceUnavailable}
+		srv := httptest.NewServer(standIn)
+		t.Cleanup(srv.Close)
+		ps := db.NewHTTPPostingService(db.PostingConfig{BaseURL: srv.URL, MaxRetries: 3, Backoff: time.Hour})
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		// Act
+		order, error := ps.NewOrder(ctx, db.Book{ID: "1"})
+
+		// Assert
+		assert.Nil(t, order)
+		assert.ErrorIs(t, error, db.ErrPostingFailed)
+		assert.ErrorContains(t, error, "context deadline exceeded")
+		assert.Equal(t, 1, standIn.calls)
+	})
+
+	t.Run("unreachable endpoint", func(t *testing.T) {
+		// Arrange
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+		ps := db.NewHTTPPostingService(db.PostingConfig{BaseURL: srv.URL, MaxRetries: 1, Backoff: time.Millisecond})
+
+		// Act
+		order, error := ps.NewOrder(context.Background(), db.Book{ID: "1"})
+
+		// Assert
+		assert.Nil(t, order)
+		assert.ErrorIs(t, error, db.ErrPostingFailed)
+	})
+}
+
+func TestHTTPPostingServiceTrack(t *testing.T) {
+	// Arrange
+	ps := newPostingService(t, &postingStandIn{}, 0)
+
+	// Act
+	order, error := ps.Track(context.Background(), "order-1")
+
+	// Assert
+	require.Nil(t, error)
+	assert.Equal(t, "IN_TRANSIT", order.Status)
+}
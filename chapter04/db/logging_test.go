@@ -0,0 +1,50 @@
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogHandler(t *testing.T) {
+	tests := map[string]struct {
+		ctx  context.Context
+		want string
+	}{
+		"request context": {
+			ctx:  db.WithRequestID(context.Background(), "request-1"),
+			want: "request-1",
+		},
+		"background context": {
+			ctx: context.Background(),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			var buf bytes.Buffer
+			logger := slog.New(db.NewLogHandler(slog.NewJSONHandler(&buf, nil))).With("service", "books")
+
+			// Act
+			logger.InfoContext(tc.ctx, "book swapped")
+
+			// Assert
+			var record map[string]any
+			require.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+			assert.Equal(t, "book swapped", record["msg"])
+			assert.Equal(t, "books", record["service"])
+			if tc.want == "" {
+				assert.NotContains(t, record, "request_id")
+			} else {
+				assert.Equal(t, tc.want, record["request_id"])
+			}
+		})
+	}
+}
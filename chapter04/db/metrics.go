@@ -0,0 +1,37 @@
+package db
+
+import (
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
+)
+
+// serviceMetrics counts the operations of the services.
+type serviceMetrics struct {
+	// swaps counts the swaps of books and the steps of swaps, by action.
+	swaps *metrics.Counter
+	// upserts counts the books and users created or updated, by entity.
+	upserts *metrics.Counter
+	// postingFailures counts the failed calls to the posting service, by operation.
+	postingFailures *metrics.Counter
+}
+
+func newServiceMetrics(reg *metrics.Registry) *serviceMetrics {
+	return &serviceMetrics{
+		swaps:           reg.Counter("bookswap_swaps_total", "Swaps of books and steps of swaps that succeeded, by action.", "action"),
+		upserts:         reg.Counter("bookswap_upserts_total", "Books and users created or updated, by entity.", "entity"),
+		postingFailures: reg.Counter("bookswap_posting_failures_total", "Calls to the posting service that failed, by operation.", "operation"),
+	}
+}
+
+// countByStatus returns the number of books that are not deleted for every status.
+func (bs *BookService) countByStatus() map[string]float64 {
+	counts := make(map[string]float64, len(bookStatusNames))
+	for _, name := range bookStatusNames {
+		counts[name] = 0
+	}
+	for _, b := range bs.books.List() {
+		if b.DeletedAt == nil {
+			counts[b.Status.String()]++
+		}
+	}
+	return counts
+}
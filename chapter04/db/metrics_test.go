@@ -0,0 +1,64 @@
+package db_test
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceMetrics(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	owner := uuid.NewString()
+	books := []db.Book{
+		{ID: uuid.NewString(), Name: "Swapped", OwnerID: owner, Status: db.Available},
+		{ID: uuid.NewString(), Name: "Requested", OwnerID: owner, Status: db.Available},
+	}
+	reg := metrics.NewRegistry()
+	ps := mocks.NewPostingService(t)
+	ps.On("NewOrder", mock.Anything, mock.Anything).Return(nil, db.ErrPostingFailed).Once()
+	bs := db.NewBookServiceWithRepository(db.NewMemoryBookRepository(books), db.NewMemoryOutboxRepository(nil), ps, slog.Default(), reg)
+	us := db.NewUserServiceWithRepository(db.NewMemoryUserRepository(nil), bs, slog.Default(), reg)
+	ss := db.NewSwapService(db.NewMemorySwapRepository(nil), bs)
+
+	// Act
+	_, err := bs.SwapBook(ctx, books[0].ID, uuid.NewString())
+	require.Nil(t, err)
+	swap, err := ss.Request(ctx, books[1].ID, uuid.NewString())
+	require.Nil(t, err)
+	_, err = ss.Accept(ctx, swap.ID, owner)
+	require.Nil(t, err)
+	_, err = bs.Upsert(ctx, db.Book{Name: "New", Author: "Author", OwnerID: owner})
+	require.Nil(t, err)
+	_, err = us.Upsert(ctx, db.User{Name: "Reader"})
+	require.Nil(t, err)
+	bs.Outbox().Dispatch(ctx, time.Now())
+
+	// Assert
+	swaps := reg.Counter("bookswap_swaps_total", "", "action")
+	assert.Equal(t, float64(1), swaps.Value("swap"))
+	assert.Equal(t, float64(1), swaps.Value("request"))
+	assert.Equal(t, float64(1), swaps.Value("accept"))
+	upserts := reg.Counter("bookswap_upserts_total", "", "entity")
+	assert.Equal(t, float64(1), upserts.Value("book"))
+	assert.Equal(t, float64(1), upserts.Value("user"))
+	assert.Equal(t, float64(1), reg.Counter("bookswap_posting_failures_total", "", "operation").Value("order"))
+
+	var b strings.Builder
+	_, err = reg.WriteTo(&b)
+	require.Nil(t, err)
+	assert.Contains(t, b.String(), `bookswap_books{status="AVAILABLE"} 1`)
+	assert.Contains(t, b.String(), `bookswap_books{status="SWAPPED"} 1`)
+	assert.Contains(t, b.String(), `bookswap_books{status="ACCEPTED"} 1`)
+	assert.Contains(t, b.String(), `bookswap_books{status="RECEIVED"} 0`)
+}
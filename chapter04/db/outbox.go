@@ -0,0 +1,356 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxDeliveryAttempts is the number of times an event is delivered
+	// before it is dead-lettered.
+	MaxDeliveryAttempts = 5
+	// deliveryBackoff is the wait before the first redelivery of an event,
+	// doubled for every further attempt.
+	deliveryBackoff = time.Second
+	// deliveryTimeout bounds a single delivery, which is not cut short by
+	// the cancellation of the dispatch, such as when the server shuts down.
+	deliveryTimeout = 30 * time.Second
+	// maxDispatchDuration is how long a dispatch may take before the
+	// dispatcher is reported as stalled.
+	maxDispatchDuration = time.Minute
+)
+
+var ErrEventNotFound = NewError(ErrNotFound, "event_not_found", "event not found")
+
+// EventStatus contains the different stages of an OutboxEvent.
+type EventStatus int
+
+const (
+	EventPending EventStatus = iota
+	EventDelivered
+	EventDead
+	EventDiscarded
+)
+
+var eventStatusNames = [...]string{"PENDING", "DELIVERED", "DEAD", "DISCARDED"}
+
+func (s EventStatus) String() string {
+	if s < 0 || int(s) >= len(eventStatusNames) {
+		return fmt.Sprintf("EventStatus(%d)", int(s))
+	}
+	return eventStatusNames[s]
+}
+
+// ParseEventStatus returns the EventStatus with the given name.
+func ParseEventStatus(name string) (EventStatus, error) {
+	for i, n := range eventStatusNames {
+		if n == name {
+			return EventStatus(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown event status %q", name)
+}
+
+// MarshalText encodes the status by name, so it appears as a string in JSON.
+func (s EventStatus) MarshalText() ([]byte, error) {
+	if s < 0 || int(s) >= len(eventStatusNames) {
+		return nil, fmt.Errorf("unknown event status %d", int(s))
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a status name, rejecting unknown statuses.
+func (s *EventStatus) UnmarshalText(text []byte) error {
+	status, err := ParseEventStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*s = status
+	return nil
+}
+
+// OutboxEvent is a posting order waiting to be sent to the PostingService.
+// Change is the status change of the book that the order belongs to.
+type OutboxEvent struct {
+	ID            string       `json:"id"`
+	Book          Book         `json:"book"`
+	SwapID        string       `json:"swap_id,omitempty"`
+	Change        StatusChange `json:"change"`
+	Status        EventStatus  `json:"status"`
+	Attempts      int          `json:"attempts"`
+	LastError     string       `json:"last_error,omitempty"`
+	OrderID       string       `json:"order_id,omitempty"`
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+	// RequestID is the ID of the request that enqueued the event, which
+	// its deliveries are logged and posted with.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Outbox delivers posting orders to the PostingService in the background.
+//
+// A change that posts a book first saves an event with the change it makes
+// to the book's history, then saves the book. The event is only delivered
+// once that change is found in the stored book, so an event whose book was
+// never saved is discarded rather than posted, and a saved book always has
+// its event: the two are recorded atomically as far as delivery goes.
+// Failed deliveries are retried with exponential backoff and dead-lettered
+// after MaxDeliveryAttempts; dead events can be replayed.
+// It is safe for concurrent use.
+type Outbox struct {
+	mu     sync.Mutex
+	events OutboxRepository
+	books  BookRepository
+	// booksMu is held while books are changed and their events enqueued,
+	// so that an event is never checked before its book has been saved.
+	booksMu sync.Locker
+	ps      PostingService
+	// dispatchMu makes sure that an event is never delivered twice at once.
+	dispatchMu sync.Mutex
+	// running is set while Run dispatches events, and dispatchStart holds
+	// the start of the dispatch under way in Unix nanoseconds, or zero.
+	running       atomic.Bool
+	dispatchStart atomic.Int64
+	wake          chan struct{}
+	// delivered is told about every order created for a swap.
+	delivered func(ctx context.Context, e OutboxEvent, o *Order)
+	logger    *slog.Logger
+	metrics   *serviceMetrics
+}
+
+func newOutbox(events OutboxRepository, books BookRepository, booksMu sync.Locker, ps PostingService, logger *slog.Logger, m *serviceMetrics) *Outbox {
+	return &Outbox{
+		events:  events,
+		books:   books,
+		booksMu: booksMu,
+		ps:      ps,
+		wake:    make(chan struct{}, 1),
+		logger:  logger,
+		metrics: m,
+	}
+}
+
+// enqueue records a posting order for b, whose history ends with the change
+// that posts it, and wakes the dispatcher.
+func (o *Outbox) enqueue(ctx context.Context, b Book, swapID string) error {
+	now := time.Now().UTC()
+	e := OutboxEvent{
+		ID:            uuid.NewString(),
+		Book:          b,
+		SwapID:        swapID,
+		Change:        b.History[len(b.History)-1],
+		Status:        EventPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		RequestID:     RequestID(ctx),
+	}
+	if err := o.events.Save(e); err != nil {
+		return err
+	}
+	o.notify()
+	return nil
+}
+
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Get returns a given event or error if none exists.
+func (o *Outbox) Get(id string) (*OutboxEvent, error) {
+	e, ok := o.events.Get(id)
+	if !ok {
+		return nil, ErrEventNotFound
+	}
+	return &e, nil
+}
+
+// List returns the events with any of the given statuses, or all events
+// when no status is given, oldest first.
+func (o *Outbox) List(statuses ...EventStatus) []OutboxEvent {
+	var items = make([]OutboxEvent, 0)
+	for _, e := range o.events.List() {
+		if len(statuses) == 0 || slices.Contains(statuses, e.Status) {
+			items = append(items, e)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+	return items
+}
+
+// Replay makes a dead event pending again, with a fresh set of attempts.
+func (o *Outbox) Replay(id string) (*OutboxEvent, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e, ok := o.events.Get(id)
+	if !ok {
+		return nil, ErrEventNotFound
+	}
+	if e.Status != EventDead {
+		return nil, fmt.Errorf("%w: cannot replay %s event", ErrInvalidTransition, e.Status)
+	}
+	now := time.Now().UTC()
+	e.Status = EventPending
+	e.Attempts = 0
+	e.NextAttemptAt = now
+	e.UpdatedAt = now
+	if err := o.events.Save(e); err != nil {
+		return nil, err
+	}
+	o.notify()
+	return &e, nil
+}
+
+// Run dispatches due events every interval, and as soon as events are
+// enqueued or replayed, until ctx is done. It returns once the delivery
+// under way, if any, is finished.
+func (o *Outbox) Run(ctx context.Context, interval time.Duration) {
+	o.running.Store(true)
+	defer o.running.Store(false)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		o.Dispatch(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// Dispatch delivers the pending events that are due at now and returns
+// how many of them were delivered. Each delivery is made with the request
+// ID of the request that enqueued the event, and with the ID of the event
+// as the idempotency key of its order.
+// Once ctx is done, Dispatch stops before the next delivery. The delivery
+// under way is finished within deliveryTimeout instead, so that it is not
+// recorded as a failed attempt.
+func (o *Outbox) Dispatch(ctx context.Context, now time.Time) int {
+	o.dispatchMu.Lock()
+	defer o.dispatchMu.Unlock()
+	o.dispatchStart.Store(time.Now().UnixNano())
+	defer o.dispatchStart.Store(0)
+
+	delivered := 0
+	for _, e := range o.List(EventPending) {
+		if ctx.Err() != nil {
+			break
+		}
+		if e.NextAttemptAt.After(now) {
+			continue
+		}
+		deliveryCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deliveryTimeout)
+		if o.deliver(WithIdempotencyKey(WithRequestID(deliveryCtx, e.RequestID), e.ID), e, now) {
+			delivered++
+		}
+		cancel()
+	}
+	return delivered
+}
+
+// Check returns an error if the dispatcher is not running, or if it has been
+// dispatching for longer than it should, such as when the posting service hangs.
+func (o *Outbox) Check() error {
+	if !o.running.Load() {
+		return fmt.Errorf("%w: not running", ErrDispatcherUnavailable)
+	}
+	if start := o.dispatchStart.Load(); start != 0 {
+		if d := time.Since(time.Unix(0, start)); d > maxDispatchDuration {
+			return fmt.Errorf("%w: dispatching for %s", ErrDispatcherUnavailable, d.Round(time.Second))
+		}
+	}
+	return checkStorage(o.events)
+}
+
+// deliver sends a single event and records the outcome.
+func (o *Outbox) deliver(ctx context.Context, e OutboxEvent, now time.Time) bool {
+	if !o.committed(e) {
+		o.finish(ctx, e, func(e *OutboxEvent) {
+			e.Status = EventDiscarded
+			e.LastError = "book change was not saved"
+		})
+		o.logger.WarnContext(ctx, "outbox event discarded", "event_id", e.ID, "book_id", e.Book.ID)
+		return false
+	}
+
+	var order *Order
+	err := errors.New("no posting service configured")
+	if o.ps != nil {
+		order, err = o.ps.NewOrder(ctx, e.Book)
+	}
+	if err != nil {
+		o.finish(ctx, e, func(e *OutboxEvent) {
+			e.Attempts++
+			e.LastError = err.Error()
+			if e.Attempts >= MaxDeliveryAttempts {
+				e.Status = EventDead
+				return
+			}
+			e.NextAttemptAt = now.Add(deliveryBackoff << (e.Attempts - 1)).UTC()
+		})
+		o.metrics.postingFailures.Inc("order")
+		if e.Attempts+1 >= MaxDeliveryAttempts {
+			o.logger.ErrorContext(ctx, "outbox event dead-lettered", "event_id", e.ID, "book_id", e.Book.ID, "error", err)
+		} else {
+			o.logger.WarnContext(ctx, "outbox delivery failed", "event_id", e.ID, "book_id", e.Book.ID, "attempt", e.Attempts+1, "error", err)
+		}
+		return false
+	}
+
+	o.finish(ctx, e, func(e *OutboxEvent) {
+		e.Attempts++
+		e.Status = EventDelivered
+		e.OrderID = order.ID
+		e.LastError = ""
+	})
+	if e.SwapID != "" && o.delivered != nil {
+		o.delivered(ctx, e, order)
+	}
+	return true
+}
+
+// finish applies the outcome of a delivery to the stored event. An outcome
+// that cannot be saved is logged, as the event is delivered again otherwise.
+func (o *Outbox) finish(ctx context.Context, e OutboxEvent, outcome func(e *OutboxEvent)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	outcome(&e)
+	e.UpdatedAt = time.Now().UTC()
+	if err := o.events.Save(e); err != nil {
+		o.logger.ErrorContext(ctx, "cannot save outbox event", "event_id", e.ID, "status", e.Status, "error", err)
+	}
+}
+
+// committed reports whether the change of an event is part of its book's history.
+func (o *Outbox) committed(e OutboxEvent) bool {
+	o.booksMu.Lock()
+	b, ok := o.books.Get(e.Book.ID)
+	o.booksMu.Unlock()
+	if !ok {
+		return false
+	}
+	for _, c := range b.History {
+		if c.From == e.Change.From && c.To == e.Change.To && c.UserID == e.Change.UserID && c.At.Equal(e.Change.At) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,373 @@
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// failingBookRepository fails every save after the initial books.
+type failingBookRepository struct {
+	db.BookRepository
+}
+
+func (failingBookRepository) Save(db.Book) error {
+	return errors.New("disk full")
+}
+
+// failingOutboxRepository fails every save after the first failAfter.
+type failingOutboxRepository struct {
+	db.OutboxRepository
+	failAfter int
+}
+
+func (r *failingOutboxRepository) Save(e db.OutboxEvent) error {
+	if r.failAfter == 0 {
+		return errors.New("disk full")
+	}
+	r.failAfter--
+	return r.OutboxRepository.Save(e)
+}
+
+func TestOutboxSwapBook(t *testing.T) {
+	t.Run("delivers the posting order", func(t *testing.T) {
+		// Arrange
+		book := db.Book{ID: uuid.NewString(), OwnerID: uuid.NewString(), Status: db.Available}
+		ps := mocks.NewPostingService(t)
+		ps.On("NewOrder", mock.Anything, mock.MatchedBy(func(b db.Book) bool { return b.ID == book.ID && b.OwnerID == book.OwnerID })).
+			Return(&db.Order{ID: "order-1", Status: db.OrderCreated}, nil).Once()
+		bs := db.NewBookService([]db.Book{book}, ps)
+		_, err := bs.SwapBook(context.Background(), book.ID, "new-owner")
+		require.Nil(t, err)
+
+		// Act
+		delivered := bs.Outbox().Dispatch(context.Background(), time.Now())
+
+		// Assert
+		assert.Equal(t, 1, delivered)
+		events := bs.Outbox().List(db.EventDelivered)
+		require.Len(t, events, 1)
+		assert.Equal(t, "order-1", events[0].OrderID)
+		assert.Equal(t, 1, events[0].Attempts)
+		assert.Equal(t, 0, bs.Outbox().Dispatch(context.Background(), time.Now()))
+	})
+
+	t.Run("delivers with the request ID of the swap", func(t *testing.T) {
+		// Arrange
+		book := db.Book{ID: uuid.NewString(), OwnerID: uuid.NewString(), Status: db.Available}
+		ps := mocks.NewPostingService(t)
+		ps.On("NewOrder", mock.MatchedBy(func(ctx context.Context) bool { return db.RequestID(ctx) == "request-1" }), mock.Anything).
+			Return(&db.Order{ID: "order-1", Status: db.OrderCreated}, nil).Once()
+		bs := db.NewBookService([]db.Book{book}, ps)
+		_, err := bs.SwapBook(db.WithRequestID(context.Background(), "request-1"), book.ID, "new-owner")
+		require.Nil(t, err)
+
+		// Act
+		delivered := bs.Outbox().Dispatch(context.Background(), time.Now())
+
+		// Assert
+		assert.Equal(t, 1, delivered)
+		assert.Equal(t, "request-1", bs.Outbox().List(db.EventDelivered)[0].RequestID)
+	})
+
+	t.Run("logs a delivery that cannot be saved", func(t *testing.T) {
+		// Arrange
+		book := db.Book{ID: uuid.NewString(), OwnerID: uuid.NewString(), Status: db.Available}
+		ps := mocks.NewPostingService(t)
+		ps.On("NewOrder", mock.Anything, mock.Anything).Return(&db.Order{ID: "order-1", Status: db.OrderCreated}, nil).Once()
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, nil))
+		events := &failingOutboxRepository{OutboxRepository: db.NewMemoryOutboxRepository(nil), failAfter: 1}
+		bs := db.NewBookServiceWithRepository(db.NewMemoryBookRepository([]db.Book{book}), events, ps, logger, metrics.NewRegistry())
+		_, err := bs.SwapBook(context.Background(), book.ID, "new-owner")
+		require.Nil(t, err)
+
+		// Act
+		delivered := bs.Outbox().Dispatch(context.Background(), time.Now())
+
+		// Assert
+		assert.Equal(t, 1, delivered)
+		pending := bs.Outbox().List(db.EventPending)
+		require.Len(t, pending, 1)
+		assert.Contains(t, logs.String(), "cannot save outbox event")
+		assert.Contains(t, logs.String(), "event_id="+pending[0].ID)
+		assert.Contains(t, logs.String(), "disk full")
+	})
+
+	t.Run("discards the order of an unsaved swap", func(t *testing.T) {
+		// Arrange
+		book := db.Book{ID: uuid.NewString(), OwnerID: uuid.NewString(), Status: db.Available}
+		ps := mocks.NewPostingService(t)
+		repo := failingBookRepository{db.NewMemoryBookRepository([]db.Book{book})}
+		bs := db.NewBookServiceWithRepository(repo, db.NewMemoryOutboxRepository(nil), ps, slog.Default(), metrics.NewRegistry())
+		_, err := bs.SwapBook(context.Background(), book.ID, "new-owner")
+		require.EqualError(t, err, "disk full")
+
+		// Act
+		delivered := bs.Outbox().Dispatch(context.Background(), time.Now())
+
+		// Assert
+		assert.Equal(t, 0, delivered)
+		assert.Len(t, bs.Outbox().List(db.EventDiscarded), 1)
+		ps.AssertNotCalled(t, "NewOrder", mock.Anything, mock.Anything)
+	})
+}
+
+func TestOutboxRetries(t *testing.T) {
+	// Arrange
+	book := db.Book{ID: uuid.NewString(), OwnerID: uuid.NewString(), Status: db.Available}
+	ps := mocks.NewPostingService(t)
+	ps.On("NewOrder", mock.Anything, mock.Anything).Return(nil, db.ErrPostingFailed).Times(db.MaxDeliveryAttempts)
+	bs := db.NewBookService([]db.Book{book}, ps)
+	_, err := bs.SwapBook(context.Background(), book.ID, "new-owner")
+	require.Nil(t, err)
+	now := time.Now()
+
+	// Act
+	assert.Equal(t, 0, bs.Outbox().Dispatch(context.Background(), now))
+	pending := bs.Outbox().List(db.EventPending)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, "posting failed", pending[0].LastError)
+	assert.True(t, pending[0].NextAttemptAt.After(now))
+
+	// The event is not retried before its backoff has passed.
+	bs.Outbox().Dispatch(context.Background(), now)
+	assert.Equal(t, 1, bs.Outbox().List(db.EventPending)[0].Attempts)
+
+	for i := 1; i < db.MaxDeliveryAttempts; i++ {
+		now = now.Add(time.Hour)
+		bs.Outbox().Dispatch(context.Background(), now)
+	}
+
+	// Assert
+	assert.Empty(t, bs.Outbox().List(db.EventPending))
+	dead := bs.Outbox().List(db.EventDead)
+	require.Len(t, dead, 1)
+	assert.Equal(t, db.MaxDeliveryAttempts, dead[0].Attempts)
+	for _, call := range ps.Calls {
+		assert.Equal(t, dead[0].ID, db.IdempotencyKey(call.Arguments.Get(0).(context.Context)))
+	}
+}
+
+func TestOutboxReplay(t *testing.T) {
+	t.Run("dead event", func(t *testing.T) {
+		// Arrange
+		book := db.Book{ID: uuid.NewString(), OwnerID: uuid.NewString(), Status: db.Available}
+		ps := mocks.NewPostingService(t)
+		ps.On("NewOrder", mock.Anything, mock.Anything).Return(nil, db.ErrPostingFailed).Times(db.MaxDeliveryAttempts)
+		bs := db.NewBookService([]db.Book{book}, ps)
+		_, err := bs.SwapBook(context.Background(), book.ID, "new-owner")
+		require.Nil(t, err)
+		now := time.Now()
+		for i := 0; i < db.MaxDeliveryAttempts; i++ {
+			bs.Outbox().Dispatch(context.Background(), now)
+			now = now.Add(time.Hour)
+		}
+		dead := bs.Outbox().List(db.EventDead)
+		require.Len(t, dead, 1)
+		ps.On("NewOrder", mock.Anything, mock.Anything).Return(&db.Order{ID: "order-1"}, nil).Once()
+
+		// Act
+		replayed, err := bs.Outbox().Replay(dead[0].ID)
+		require.Nil(t, err)
+		delivered := bs.Outbox().Dispatch(context.Background(), time.Now())
+
+		// Assert
+		assert.Equal(t, db.EventPending, replayed.Status)
+		assert.Equal(t, 0, replayed.Attempts)
+		assert.Equal(t, 1, delivered)
+		event, err := bs.Outbox().Get(dead[0].ID)
+		require.Nil(t, err)
+		assert.Equal(t, db.EventDelivered, event.Status)
+	})
+
+	t.Run("pending event", func(t *testing.T) {
+		// Arrange
+		book := db.Book{ID: uuid.NewString(), OwnerID: uuid.NewString(), Status: db.Available}
+		bs := db.NewBookService([]db.Book{book}, nil)
+		_, err := bs.SwapBook(context.Background(), book.ID, "new-owner")
+		require.Nil(t, err)
+
+		// Act
+		_, err = bs.Outbox().Replay(bs.Outbox().List()[0].ID)
+
+		// Assert
+		assert.ErrorIs(t, err, db.ErrInvalidTransition)
+	})
+
+	t.Run("unknown event", func(t *testing.T) {
+		bs := db.NewBookService(nil, nil)
+		_, err := bs.Outbox().Replay("not-found")
+		assert.ErrorIs(t, err, db.ErrEventNotFound)
+	})
+}
+
+func TestOutboxShip(t *testing.T) {
+	// Arrange
+	book := db.Book{ID: uuid.NewString(), OwnerID: uuid.NewString(), Status: db.Available}
+	requesterID := uuid.NewString()
+	ps := mocks.NewPostingService(t)
+	bs := db.NewBookService([]db.Book{book}, ps)
+	ss := db.NewSwapService(db.NewMemorySwapRepository(nil), bs)
+	swap, err := ss.Request(context.Background(), book.ID, requesterID)
+	require.Nil(t, err)
+	_, err = ss.Accept(context.Background(), swap.ID, book.OwnerID)
+	require.Nil(t, err)
+	ps.On("NewOrder", mock.Anything, mock.MatchedBy(func(b db.Book) bool { return b.ID == book.ID })).
+		Return(&db.Order{ID: "order-1", Status: db.OrderCreated}, nil).Once()
+	ps.On("Track", mock.Anything, "order-1").Return(&db.Order{ID: "order-1", Status: "DELIVERED"}, nil).Once()
+
+	// Act
+	shipped, err := ss.Ship(context.Background(), swap.ID, book.OwnerID)
+	require.Nil(t, err)
+	assert.Nil(t, shipped.Order)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		bs.Outbox().Run(ctx, time.Hour)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		s, err := ss.Get(context.Background(), swap.ID, requesterID)
+		return err == nil && s.Order != nil
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+	tracked, err := ss.Track(context.Background(), swap.ID, requesterID)
+
+	// Assert
+	require.Nil(t, err)
+	assert.Equal(t, db.SwapShipped, tracked.Status)
+	assert.Equal(t, "DELIVERED", tracked.Order.Status)
+}
+
+func TestOutboxStopsBetweenDeliveries(t *testing.T) {
+	// Arrange
+	books := []db.Book{
+		{ID: uuid.NewString(), OwnerID: uuid.NewString(), Status: db.Available},
+		{ID: uuid.NewString(), OwnerID: uuid.NewString(), Status: db.Available},
+	}
+	ps := mocks.NewPostingService(t)
+	bs := db.NewBookService(books, ps)
+	for _, b := range books {
+		_, err := bs.SwapBook(context.Background(), b.ID, "new-owner")
+		require.Nil(t, err)
+	}
+	calling, posted := make(chan struct{}), make(chan struct{})
+	ps.On("NewOrder", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		close(calling)
+		<-posted
+		assert.Nil(t, args.Get(0).(context.Context).Err())
+	}).
+		Return(&db.Order{ID: "order-1", Status: db.OrderCreated}, nil).Once()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		bs.Outbox().Run(ctx, time.Hour)
+		close(done)
+	}()
+	<-calling
+
+	// Act
+	cancel()
+	close(posted)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "Run did not return after its delivery")
+	}
+
+	// Assert
+	delivered := bs.Outbox().List(db.EventDelivered)
+	require.Len(t, delivered, 1)
+	assert.Equal(t, "order-1", delivered[0].OrderID)
+	pending := bs.Outbox().List(db.EventPending)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 0, pending[0].Attempts)
+}
+
+func TestTrackDoesNotHoldUpSwaps(t *testing.T) {
+	// Arrange
+	shipped := db.Book{ID: uuid.NewString(), OwnerID: uuid.NewString(), Status: db.Available}
+	other := db.Book{ID: uuid.NewString(), OwnerID: uuid.NewString(), Status: db.Available}
+	requesterID := uuid.NewString()
+	ps := mocks.NewPostingService(t)
+	bs := db.NewBookService([]db.Book{shipped, other}, ps)
+	ss := db.NewSwapService(db.NewMemorySwapRepository(nil), bs)
+	swap, err := ss.Request(context.Background(), shipped.ID, requesterID)
+	require.Nil(t, err)
+	_, err = ss.Accept(context.Background(), swap.ID, shipped.OwnerID)
+	require.Nil(t, err)
+	_, err = ss.Ship(context.Background(), swap.ID, shipped.OwnerID)
+	require.Nil(t, err)
+	ps.On("NewOrder", mock.Anything, mock.Anything).Return(&db.Order{ID: "order-1", Status: db.OrderCreated}, nil).Once()
+	require.Equal(t, 1, bs.Outbox().Dispatch(context.Background(), time.Now()))
+	calling, carrier := make(chan struct{}), make(chan struct{})
+	ps.On("Track", mock.Anything, "order-1").Run(func(mock.Arguments) {
+		close(calling)
+		<-carrier
+	}).
+		Return(&db.Order{ID: "order-1", Status: "IN_TRANSIT"}, nil).Once()
+	tracked := make(chan *db.Swap)
+	go func() {
+		s, err := ss.Track(context.Background(), swap.ID, requesterID)
+		assert.Nil(t, err)
+		tracked <- s
+	}()
+	<-calling
+
+	// Act
+	requested, err := ss.Request(context.Background(), other.ID, requesterID)
+
+	// Assert
+	require.Nil(t, err)
+	assert.Equal(t, db.SwapRequested, requested.Status)
+	close(carrier)
+	s := <-tracked
+	require.NotNil(t, s)
+	assert.Equal(t, "IN_TRANSIT", s.Order.Status)
+	assert.Equal(t, db.SwapShipped, s.Status)
+}
+
+func TestEventStatusJSON(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		// Arrange
+		event := db.OutboxEvent{ID: "1", Status: db.EventDead}
+
+		// Act
+		data, err := json.Marshal(event)
+		require.Nil(t, err)
+		var decoded db.OutboxEvent
+		err = json.Unmarshal(data, &decoded)
+
+		// Assert
+		require.Nil(t, err)
+		assert.Contains(t, string(data), `"status":"DEAD"`)
+		assert.Equal(t, event, decoded)
+	})
+
+	t.Run("unknown status", func(t *testing.T) {
+		var event db.OutboxEvent
+		err := json.Unmarshal([]byte(`{"status":"LOST"}`), &event)
+		assert.ErrorContains(t, err, `unknown event status "LOST"`)
+	})
+
+	t.Run("invalid status value", func(t *testing.T) {
+		assert.Equal(t, "EventStatus(42)", db.EventStatus(42).String())
+		_, err := json.Marshal(db.OutboxEvent{Status: db.EventStatus(42)})
+		assert.ErrorContains(t, err, "unknown event status 42")
+	})
+}
@@ -1,24 +1,72 @@
 package db
 
 import (
-	"log"
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
 )
 
+// Order is a posting order for a book, as reported by the posting service.
+type Order struct {
+	ID      string `json:"id"`
+	Carrier string `json:"carrier,omitempty"`
+	Status  string `json:"status"`
+}
+
+// ErrPostingFailed is returned when the posting service cannot create or track an order.
+var ErrPostingFailed = NewError(ErrUnavailable, "posting_failed", "posting failed")
+
+// OrderCreated is the status of an order that has not been picked up yet.
+const OrderCreated = "CREATED"
+
+// idempotencyKeyKey is the context key of the idempotency key of an order.
+type idempotencyKeyKey struct{}
+
+// WithIdempotencyKey returns a copy of ctx that carries the key under which
+// NewOrder creates its order, so that an order created again under the same
+// key, such as a redelivery of the outbox, is only created once.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+// IdempotencyKey returns the idempotency key that ctx carries, or an empty string.
+func IdempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyKey{}).(string)
+	return key
+}
+
 // PostingService interface wraps around external posting functionality.
 type PostingService interface {
-	NewOrder(b Book) error
+	NewOrder(ctx context.Context, b Book) (*Order, error)
+	Track(ctx context.Context, orderID string) (*Order, error)
 }
 
 // StubbedPostingService is a concrete mock of the external PostingService.
-type StubbedPostingService struct{}
+type StubbedPostingService struct {
+	logger *slog.Logger
+}
 
-// NewPostingService initialises the PostingService.
+// NewPostingService initialises the PostingService, which logs to the default logger.
 func NewPostingService() PostingService {
-	return &StubbedPostingService{}
+	return &StubbedPostingService{logger: slog.Default()}
 }
 
 // NewOrder creates a new order and sends it to the posting servivce for posting.
-func (sps *StubbedPostingService) NewOrder(b Book) error {
-	log.Printf("STUBBED POSTING SERVICE: book %s posted: %v", b.ID, b)
-	return nil
+func (sps *StubbedPostingService) NewOrder(ctx context.Context, b Book) (*Order, error) {
+	sps.logger.InfoContext(ctx, "stubbed posting service posted book", "book_id", b.ID, "name", b.Name)
+	return &Order{
+		ID:      uuid.NewString(),
+		Carrier: "stub",
+		Status:  OrderCreated,
+	}, nil
+}
+
+// Track returns the latest status of an order.
+func (sps *StubbedPostingService) Track(ctx context.Context, orderID string) (*Order, error) {
+	return &Order{
+		ID:      orderID,
+		Carrier: "stub",
+		Status:  OrderCreated,
+	}, nil
 }
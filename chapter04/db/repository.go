@@ -0,0 +1,123 @@
+package db
+
+import (
+	"sync"
+)
+
+// BookRepository stores books by ID. Implementations must be safe for concurrent use.
+type BookRepository interface {
+	Get(id string) (Book, bool)
+	List() []Book
+	Save(b Book) error
+}
+
+// UserRepository stores users by ID. Implementations must be safe for concurrent use.
+type UserRepository interface {
+	Get(id string) (User, bool)
+	List() []User
+	Save(u User) error
+}
+
+// SwapRepository stores swaps by ID. Implementations must be safe for concurrent use.
+type SwapRepository interface {
+	Get(id string) (Swap, bool)
+	List() []Swap
+	Save(s Swap) error
+}
+
+// OutboxRepository stores outbox events by ID. Implementations must be safe for concurrent use.
+type OutboxRepository interface {
+	Get(id string) (OutboxEvent, bool)
+	List() []OutboxEvent
+	Save(e OutboxEvent) error
+}
+
+// CredentialRepository stores credentials by user ID. Implementations must be safe for concurrent use.
+type CredentialRepository interface {
+	Get(userID string) (Credential, bool)
+	Save(c Credential) error
+}
+
+func bookID(b Book) string { return b.ID }
+
+func userID(u User) string { return u.ID }
+
+func swapID(s Swap) string { return s.ID }
+
+func eventID(e OutboxEvent) string { return e.ID }
+
+func credentialID(c Credential) string { return c.UserID }
+
+// NewMemoryBookRepository returns a BookRepository that keeps books in memory only.
+func NewMemoryBookRepository(initial []Book) BookRepository {
+	return newMemoryStore(initial, bookID)
+}
+
+// NewMemoryUserRepository returns a UserRepository that keeps users in memory only.
+func NewMemoryUserRepository(initial []User) UserRepository {
+	return newMemoryStore(initial, userID)
+}
+
+// NewMemorySwapRepository returns a SwapRepository that keeps swaps in memory only.
+func NewMemorySwapRepository(initial []Swap) SwapRepository {
+	return newMemoryStore(initial, swapID)
+}
+
+// NewMemoryOutboxRepository returns an OutboxRepository that keeps events in memory only.
+func NewMemoryOutboxRepository(initial []OutboxEvent) OutboxRepository {
+	return newMemoryStore(initial, eventID)
+}
+
+// NewMemoryCredentialRepository returns a CredentialRepository that keeps credentials in memory only.
+func NewMemoryCredentialRepository(initial []Credential) CredentialRepository {
+	return newMemoryStore(initial, credentialID)
+}
+
+// memoryStore is a map of records keyed by ID that is safe for concurrent use.
+type memoryStore[T any] struct {
+	mu      sync.RWMutex
+	records map[string]T
+	id      func(T) string
+}
+
+func newMemoryStore[T any](initial []T, id func(T) string) *memoryStore[T] {
+	records := make(map[string]T)
+	for _, r := range initial {
+		records[id(r)] = r
+	}
+	return &memoryStore[T]{
+		records: records,
+		id:      id,
+	}
+}
+
+func (ms *memoryStore[T]) Get(id string) (T, bool) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	r, ok := ms.records[id]
+	return r, ok
+}
+
+func (ms *memoryStore[T]) List() []T {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	items := make([]T, 0, len(ms.records))
+	for _, r := range ms.records {
+		items = append(items, r)
+	}
+	return items
+}
+
+// len returns the number of records without copying them.
+func (ms *memoryStore[T]) len() int {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return len(ms.records)
+}
+
+func (ms *memoryStore[T]) Save(r T) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.records[ms.id(r)] = r
+	return nil
+}
@@ -0,0 +1,299 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrBookNotFound      = NewError(ErrNotFound, "book_not_found", "book doesn't exist")
+	ErrSwapNotFound      = NewError(ErrNotFound, "swap_not_found", "swap not found")
+	ErrNotAllowed        = NewError(ErrForbidden, "not_allowed", "user is not allowed to perform this action")
+	ErrInvalidTransition = NewError(ErrConflict, "invalid_transition", "invalid status transition")
+)
+
+// Swap is a request by one user for the book of another.
+type Swap struct {
+	ID          string     `json:"id"`
+	BookID      string     `json:"book_id"`
+	OwnerID     string     `json:"owner_id"`
+	RequesterID string     `json:"requester_id"`
+	Status      SwapStatus `json:"status"`
+	Order       *Order     `json:"order,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// step is a transition of the swap lifecycle and of the book being swapped.
+type step struct {
+	action string
+	from   []SwapStatus
+	to     SwapStatus
+	book   BookStatus
+	actor  func(s Swap, userID string) bool
+}
+
+var (
+	acceptStep  = step{action: "accept", from: []SwapStatus{SwapRequested}, to: SwapAccepted, book: Accepted, actor: isOwner}
+	rejectStep  = step{action: "reject", from: []SwapStatus{SwapRequested}, to: SwapRejected, book: Available, actor: isOwner}
+	shipStep    = step{action: "ship", from: []SwapStatus{SwapAccepted}, to: SwapShipped, book: Shipped, actor: isOwner}
+	receiveStep = step{action: "receive", from: []SwapStatus{SwapShipped}, to: SwapReceived, book: Received, actor: isRequester}
+	cancelStep  = step{action: "cancel", from: []SwapStatus{SwapRequested, SwapAccepted}, to: SwapCancelled, book: Available, actor: isParty}
+)
+
+func isOwner(s Swap, userID string) bool { return s.OwnerID == userID }
+
+func isRequester(s Swap, userID string) bool { return s.RequesterID == userID }
+
+func isParty(s Swap, userID string) bool { return isOwner(s, userID) || isRequester(s, userID) }
+
+// bookStatusDuring maps the status of an open swap to the status of its book.
+var bookStatusDuring = map[SwapStatus]BookStatus{
+	SwapRequested: Requested,
+	SwapAccepted:  Accepted,
+	SwapShipped:   Shipped,
+}
+
+// SwapService manages the lifecycle of swaps: a user requests a book, the
+// owner accepts or rejects the request, ships the book once accepted and the
+// requester confirms its receipt, which makes them the new owner. Either
+// party can cancel the swap before the book is shipped.
+// It is safe for concurrent use.
+type SwapService struct {
+	mu    sync.Mutex
+	swaps SwapRepository
+	bs    *BookService
+}
+
+// NewSwapService creates a SwapService that stores swaps in repo.
+// It records the posting orders delivered by the outbox of bs on their swaps.
+func NewSwapService(repo SwapRepository, bs *BookService) *SwapService {
+	ss := &SwapService{
+		swaps: repo,
+		bs:    bs,
+	}
+	bs.outbox.delivered = ss.recordOrder
+	return ss
+}
+
+// Get returns a given swap to either of its parties, or an error if none
+// exists or userID is not a party to it.
+func (ss *SwapService) Get(ctx context.Context, swapID, userID string) (*Swap, error) {
+	s, ok := ss.swaps.Get(swapID)
+	if !ok {
+		return nil, ErrSwapNotFound
+	}
+	if !isParty(s, userID) {
+		return nil, fmt.Errorf("%w: cannot view swap", ErrNotAllowed)
+	}
+	return &s, nil
+}
+
+// ListByUser returns the swaps a given user owns or requested, oldest first.
+func (ss *SwapService) ListByUser(ctx context.Context, userID string) []Swap {
+	var items = make([]Swap, 0)
+	for _, s := range ss.swaps.List() {
+		if isParty(s, userID) {
+			items = append(items, s)
+		}
+	}
+	sortSwaps(items)
+	return items
+}
+
+// Request asks the owner of an available book to swap it with requesterID.
+func (ss *SwapService) Request(ctx context.Context, bookID, requesterID string) (*Swap, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	var previous Book
+	book, err := ss.bs.update(bookID, func(b *Book) error {
+		previous = *b
+		if b.OwnerID == requesterID {
+			return fmt.Errorf("%w: cannot request your own book", ErrNotAllowed)
+		}
+		if b.Status != Available {
+			return fmt.Errorf("%w: book is not available", ErrInvalidTransition)
+		}
+		return setStatus(b, Requested, requesterID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	swap := Swap{
+		ID:          uuid.NewString(),
+		BookID:      book.ID,
+		OwnerID:     book.OwnerID,
+		RequesterID: requesterID,
+		Status:      SwapRequested,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := ss.swaps.Save(swap); err != nil {
+		ss.restore(previous)
+		return nil, err
+	}
+	ss.bs.logger.InfoContext(ctx, "swap requested", "swap_id", swap.ID, "book_id", book.ID, "user_id", requesterID)
+	ss.bs.metrics.swaps.Inc("request")
+	return &swap, nil
+}
+
+// Accept is called by the owner to agree to a requested swap.
+func (ss *SwapService) Accept(ctx context.Context, swapID, userID string) (*Swap, error) {
+	return ss.advance(ctx, swapID, userID, acceptStep)
+}
+
+// Reject is called by the owner to turn down a requested swap.
+func (ss *SwapService) Reject(ctx context.Context, swapID, userID string) (*Swap, error) {
+	return ss.advance(ctx, swapID, userID, rejectStep)
+}
+
+// Ship is called by the owner to post an accepted book. It records a posting
+// order in the outbox, which is kept on the swap once it has been delivered.
+func (ss *SwapService) Ship(ctx context.Context, swapID, userID string) (*Swap, error) {
+	return ss.advance(ctx, swapID, userID, shipStep)
+}
+
+// Receive is called by the requester once the book has arrived and
+// transfers the ownership of the book to them.
+func (ss *SwapService) Receive(ctx context.Context, swapID, userID string) (*Swap, error) {
+	return ss.advance(ctx, swapID, userID, receiveStep)
+}
+
+// Cancel is called by either party to call off a swap before shipping.
+func (ss *SwapService) Cancel(ctx context.Context, swapID, userID string) (*Swap, error) {
+	return ss.advance(ctx, swapID, userID, cancelStep)
+}
+
+// Track refreshes the tracking status of the posting order of a swap.
+// The posting service is called without holding the lock of the swaps,
+// so that a slow carrier does not hold up the other swaps.
+func (ss *SwapService) Track(ctx context.Context, swapID, userID string) (*Swap, error) {
+	ss.mu.Lock()
+	swap, ok := ss.swaps.Get(swapID)
+	ss.mu.Unlock()
+	if !ok {
+		return nil, ErrSwapNotFound
+	}
+	if !isParty(swap, userID) {
+		return nil, fmt.Errorf("%w: cannot track swap", ErrNotAllowed)
+	}
+	if swap.Order == nil || ss.bs.ps == nil {
+		return &swap, nil
+	}
+
+	order, err := ss.bs.ps.Track(ctx, swap.Order.ID)
+	if err != nil {
+		ss.bs.metrics.postingFailures.Inc("track")
+		return nil, err
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	// The swap may have moved on while the order was tracked.
+	swap, ok = ss.swaps.Get(swapID)
+	if !ok {
+		return nil, ErrSwapNotFound
+	}
+	swap.Order = order
+	swap.UpdatedAt = time.Now().UTC()
+	if err := ss.swaps.Save(swap); err != nil {
+		return nil, err
+	}
+	return &swap, nil
+}
+
+// recordOrder keeps the posting order delivered for a swap on it.
+func (ss *SwapService) recordOrder(ctx context.Context, e OutboxEvent, order *Order) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	swap, ok := ss.swaps.Get(e.SwapID)
+	if !ok {
+		return
+	}
+	swap.Order = order
+	swap.UpdatedAt = time.Now().UTC()
+	if err := ss.swaps.Save(swap); err != nil {
+		ss.bs.logger.ErrorContext(ctx, "cannot record order on swap", "event_id", e.ID, "swap_id", swap.ID, "order_id", order.ID, "error", err)
+	}
+}
+
+// advance moves a swap and its book through st, provided userID may take it.
+func (ss *SwapService) advance(ctx context.Context, swapID, userID string, st step) (*Swap, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	swap, ok := ss.swaps.Get(swapID)
+	if !ok {
+		return nil, ErrSwapNotFound
+	}
+	if !st.actor(swap, userID) {
+		return nil, fmt.Errorf("%w: cannot %s swap", ErrNotAllowed, st.action)
+	}
+	if !st.allows(swap.Status) {
+		return nil, fmt.Errorf("%w: cannot %s %s swap", ErrInvalidTransition, st.action, swap.Status)
+	}
+
+	var previous Book
+	_, err := ss.bs.update(swap.BookID, func(b *Book) error {
+		previous = *b
+		if want := bookStatusDuring[swap.Status]; b.Status != want {
+			return fmt.Errorf("%w: book is %s, want %s", ErrInvalidTransition, b.Status, want)
+		}
+		if err := setStatus(b, st.book, userID); err != nil {
+			return err
+		}
+		if st.to == SwapShipped {
+			if err := ss.bs.outbox.enqueue(ctx, *b, swap.ID); err != nil {
+				return err
+			}
+		}
+		if st.to == SwapReceived {
+			b.OwnerID = swap.RequesterID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	swap.Status = st.to
+	swap.UpdatedAt = time.Now().UTC()
+	if err := ss.swaps.Save(swap); err != nil {
+		ss.restore(previous)
+		return nil, err
+	}
+	ss.bs.metrics.swaps.Inc(st.action)
+	return &swap, nil
+}
+
+func (st step) allows(status SwapStatus) bool {
+	for _, s := range st.from {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// restore puts a book back the way it was when its swap could not be saved.
+func (ss *SwapService) restore(previous Book) {
+	ss.bs.update(previous.ID, func(b *Book) error {
+		*b = previous
+		return nil
+	})
+}
+
+func sortSwaps(swaps []Swap) {
+	sort.Slice(swaps, func(i, j int) bool {
+		return swaps[i].CreatedAt.Before(swaps[j].CreatedAt)
+	})
+}
@@ -0,0 +1,195 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSwap(t *testing.T) (*db.SwapService, *db.BookService, db.Book, string) {
+	t.Helper()
+	book := db.Book{
+		ID:      uuid.NewString(),
+		Name:    "Swappable",
+		OwnerID: uuid.NewString(),
+		Status:  db.Available,
+	}
+	bs := db.NewBookService([]db.Book{book}, nil)
+	ss := db.NewSwapService(db.NewMemorySwapRepository(nil), bs)
+	return ss, bs, book, uuid.NewString()
+}
+
+func TestSwapLifecycle(t *testing.T) {
+	// Arrange
+	ss, bs, book, requesterID := setupSwap(t)
+
+	// Act
+	swap, error := ss.Request(context.Background(), book.ID, requesterID)
+	require.Nil(t, error)
+	assert.Equal(t, db.SwapRequested, swap.Status)
+	assertBookStatus(t, bs, book.ID, db.Requested)
+
+	swap, error = ss.Accept(context.Background(), swap.ID, book.OwnerID)
+	require.Nil(t, error)
+	assert.Equal(t, db.SwapAccepted, swap.Status)
+	assertBookStatus(t, bs, book.ID, db.Accepted)
+
+	swap, error = ss.Ship(context.Background(), swap.ID, book.OwnerID)
+	require.Nil(t, error)
+	assert.Equal(t, db.SwapShipped, swap.Status)
+	assertBookStatus(t, bs, book.ID, db.Shipped)
+
+	swap, error = ss.Receive(context.Background(), swap.ID, requesterID)
+
+	// Assert
+	require.Nil(t, error)
+	assert.Equal(t, db.SwapReceived, swap.Status)
+	got := assertBookStatus(t, bs, book.ID, db.Received)
+	assert.Equal(t, requesterID, got.OwnerID)
+	assert.Equal(t, []db.Swap{*swap}, ss.ListByUser(context.Background(), requesterID))
+	assert.Equal(t, []db.Swap{*swap}, ss.ListByUser(context.Background(), book.OwnerID))
+}
+
+func TestSwapEndsEarly(t *testing.T) {
+	tests := map[string]struct {
+		accept     bool
+		end        func(ss *db.SwapService, swapID, ownerID, requesterID string) (*db.Swap, error)
+		wantStatus db.SwapStatus
+	}{
+		"owner rejects": {
+			end: func(ss *db.SwapService, swapID, ownerID, _ string) (*db.Swap, error) {
+				return ss.Reject(context.Background(), swapID, ownerID)
+			},
+			wantStatus: db.SwapRejected,
+		},
+		"requester cancels": {
+			end: func(ss *db.SwapService, swapID, _, requesterID string) (*db.Swap, error) {
+				return ss.Cancel(context.Background(), swapID, requesterID)
+			},
+			wantStatus: db.SwapCancelled,
+		},
+		"owner cancels accepted": {
+			accept: true,
+			end: func(ss *db.SwapService, swapID, ownerID, _ string) (*db.Swap, error) {
+				return ss.Cancel(context.Background(), swapID, ownerID)
+			},
+			wantStatus: db.SwapCancelled,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			ss, bs, book, requesterID := setupSwap(t)
+			swap, error := ss.Request(context.Background(), book.ID, requesterID)
+			require.Nil(t, error)
+			if tc.accept {
+				_, error = ss.Accept(context.Background(), swap.ID, book.OwnerID)
+				require.Nil(t, error)
+			}
+
+			// Act
+			swap, error = tc.end(ss, swap.ID, book.OwnerID, requesterID)
+
+			// Assert
+			require.Nil(t, error)
+			assert.Equal(t, tc.wantStatus, swap.Status)
+			got := assertBookStatus(t, bs, book.ID, db.Available)
+			assert.Equal(t, book.OwnerID, got.OwnerID)
+		})
+	}
+}
+
+func TestSwapErrors(t *testing.T) {
+	t.Run("request own book", func(t *testing.T) {
+		ss, _, book, _ := setupSwap(t)
+		_, error := ss.Request(context.Background(), book.ID, book.OwnerID)
+		assert.ErrorIs(t, error, db.ErrNotAllowed)
+	})
+
+	t.Run("request unknown book", func(t *testing.T) {
+		ss, _, _, requesterID := setupSwap(t)
+		_, error := ss.Request(context.Background(), "not-found", requesterID)
+		assert.ErrorIs(t, error, db.ErrBookNotFound)
+	})
+
+	t.Run("request requested book", func(t *testing.T) {
+		ss, _, book, requesterID := setupSwap(t)
+		_, error := ss.Request(context.Background(), book.ID, requesterID)
+		require.Nil(t, error)
+		_, error = ss.Request(context.Background(), book.ID, uuid.NewString())
+		assert.ErrorIs(t, error, db.ErrInvalidTransition)
+	})
+
+	t.Run("requester cannot accept", func(t *testing.T) {
+		ss, _, book, requesterID := setupSwap(t)
+		swap, error := ss.Request(context.Background(), book.ID, requesterID)
+		require.Nil(t, error)
+		_, error = ss.Accept(context.Background(), swap.ID, requesterID)
+		assert.ErrorIs(t, error, db.ErrNotAllowed)
+	})
+
+	t.Run("ship before accept", func(t *testing.T) {
+		ss, _, book, requesterID := setupSwap(t)
+		swap, error := ss.Request(context.Background(), book.ID, requesterID)
+		require.Nil(t, error)
+		_, error = ss.Ship(context.Background(), swap.ID, book.OwnerID)
+		assert.ErrorIs(t, error, db.ErrInvalidTransition)
+		assert.EqualError(t, error, "invalid status transition: cannot ship REQUESTED swap")
+	})
+
+	t.Run("cancel after shipping", func(t *testing.T) {
+		ss, _, book, requesterID := setupSwap(t)
+		swap, error := ss.Request(context.Background(), book.ID, requesterID)
+		require.Nil(t, error)
+		_, error = ss.Accept(context.Background(), swap.ID, book.OwnerID)
+		require.Nil(t, error)
+		_, error = ss.Ship(context.Background(), swap.ID, book.OwnerID)
+		require.Nil(t, error)
+		_, error = ss.Cancel(context.Background(), swap.ID, requesterID)
+		assert.ErrorIs(t, error, db.ErrInvalidTransition)
+	})
+
+	t.Run("unknown swap", func(t *testing.T) {
+		ss, _, book, _ := setupSwap(t)
+		_, error := ss.Accept(context.Background(), "not-found", book.OwnerID)
+		assert.ErrorIs(t, error, db.ErrSwapNotFound)
+		_, error = ss.Get(context.Background(), "not-found", book.OwnerID)
+		assert.ErrorIs(t, error, db.ErrSwapNotFound)
+	})
+
+	t.Run("only parties can get a swap", func(t *testing.T) {
+		ss, _, book, requesterID := setupSwap(t)
+		swap, error := ss.Request(context.Background(), book.ID, requesterID)
+		require.Nil(t, error)
+		for _, userID := range []string{book.OwnerID, requesterID} {
+			got, error := ss.Get(context.Background(), swap.ID, userID)
+			require.Nil(t, error)
+			assert.Equal(t, swap, got)
+		}
+		_, error = ss.Get(context.Background(), swap.ID, uuid.NewString())
+		assert.ErrorIs(t, error, db.ErrNotAllowed)
+		_, error = ss.Get(context.Background(), swap.ID, "")
+		assert.ErrorIs(t, error, db.ErrNotAllowed)
+	})
+
+	t.Run("requested book cannot be swapped directly", func(t *testing.T) {
+		ss, bs, book, requesterID := setupSwap(t)
+		_, error := ss.Request(context.Background(), book.ID, requesterID)
+		require.Nil(t, error)
+		_, error = bs.SwapBook(context.Background(), book.ID, uuid.NewString())
+		assert.EqualError(t, error, "book is not available")
+	})
+}
+
+func assertBookStatus(t *testing.T, bs *db.BookService, bookID string, want db.BookStatus) *db.Book {
+	t.Helper()
+	book, err := bs.Get(context.Background(), bookID)
+	require.Nil(t, err)
+	assert.Equal(t, want, book.Status)
+	return book
+}
@@ -0,0 +1,52 @@
+package db
+
+import "fmt"
+
+// SwapStatus contains the different stages of a Swap.
+type SwapStatus int
+
+const (
+	SwapRequested SwapStatus = iota
+	SwapAccepted
+	SwapRejected
+	SwapShipped
+	SwapReceived
+	SwapCancelled
+)
+
+var swapStatusNames = [...]string{"REQUESTED", "ACCEPTED", "REJECTED", "SHIPPED", "RECEIVED", "CANCELLED"}
+
+func (s SwapStatus) String() string {
+	if s < 0 || int(s) >= len(swapStatusNames) {
+		return fmt.Sprintf("SwapStatus(%d)", int(s))
+	}
+	return swapStatusNames[s]
+}
+
+// ParseSwapStatus returns the SwapStatus with the given name.
+func ParseSwapStatus(name string) (SwapStatus, error) {
+	for i, n := range swapStatusNames {
+		if n == name {
+			return SwapStatus(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown swap status %q", name)
+}
+
+// MarshalText encodes the status by name, so it appears as a string in JSON.
+func (s SwapStatus) MarshalText() ([]byte, error) {
+	if s < 0 || int(s) >= len(swapStatusNames) {
+		return nil, fmt.Errorf("unknown swap status %d", int(s))
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a status name, rejecting unknown statuses.
+func (s *SwapStatus) UnmarshalText(text []byte) error {
+	status, err := ParseSwapStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*s = status
+	return nil
+}
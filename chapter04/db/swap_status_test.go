@@ -0,0 +1,44 @@
+package db_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapStatusString(t *testing.T) {
+	assert.Equal(t, "CANCELLED", db.SwapCancelled.String())
+	assert.Equal(t, "SwapStatus(42)", db.SwapStatus(42).String())
+}
+
+func TestSwapStatusJSON(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		// Arrange
+		swap := db.Swap{ID: "1", Status: db.SwapShipped}
+
+		// Act
+		data, error := json.Marshal(swap)
+		require.Nil(t, error)
+		var decoded db.Swap
+		error = json.Unmarshal(data, &decoded)
+
+		// Assert
+		require.Nil(t, error)
+		assert.Contains(t, string(data), `"status":"SHIPPED"`)
+		assert.Equal(t, swap, decoded)
+	})
+
+	t.Run("unknown status", func(t *testing.T) {
+		var swap db.Swap
+		error := json.Unmarshal([]byte(`{"status":"LOST"}`), &swap)
+		assert.ErrorContains(t, error, `unknown swap status "LOST"`)
+	})
+
+	t.Run("invalid status value", func(t *testing.T) {
+		_, error := json.Marshal(db.Swap{Status: db.SwapStatus(42)})
+		assert.ErrorContains(t, error, "unknown swap status 42")
+	})
+}
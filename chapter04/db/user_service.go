@@ -1,68 +1,172 @@
 package db
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
 	"github.com/google/uuid"
 )
 
+var (
+	ErrUserNotFound = NewError(ErrNotFound, "user_not_found", "user does not exist")
+	ErrUserHasBooks = NewError(ErrConflict, "user_has_books", "user still owns books")
+)
+
 // User contains all the user fields.
 type User struct {
 	ID       string `json:"id"`
-	Name     string `json:"name"`
-	Address  string `json:"address"`
-	PostCode string `json:"post_code"`
-	Country  string `json:"country"`
+	Name     string `json:"name" validate:"required,max=100"`
+	Address  string `json:"address" validate:"required,max=200"`
+	PostCode string `json:"post_code" validate:"required,postcode=Country"`
+	// Country is an ISO 3166-1 alpha-2 code, such as MK.
+	Country string `json:"country" validate:"required,country"`
+	// DeletedAt is set when the user deletes their account.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Validate returns a *ValidationError listing the invalid fields of the user.
+func (u User) Validate() error {
+	return validate(u)
 }
 
 type BookOperationsService interface {
-	ListByUser(userID string) []Book
+	ListByUser(ctx context.Context, userID string) []Book
 }
 
 // UserService has all the dependencies required for managing users.
+// It is safe for concurrent use as long as its repository is.
 type UserService struct {
-	users map[string]User
-	bs    BookOperationsService
+	// mu serialises the read-modify-write operations on users.
+	mu      sync.Mutex
+	users   UserRepository
+	bs      BookOperationsService
+	logger  *slog.Logger
+	metrics *serviceMetrics
 }
 
+// NewUserService creates a UserService that keeps the initial users in memory,
+// logs to the default logger and keeps its metrics in a registry of its own.
 func NewUserService(initial []User, bookOperationService BookOperationsService) *UserService {
-	users := make(map[string]User)
-	for _, u := range initial {
-		users[u.ID] = u
-	}
+	return NewUserServiceWithRepository(NewMemoryUserRepository(initial), bookOperationService, slog.Default(), metrics.NewRegistry())
+}
 
+// NewUserServiceWithRepository creates a UserService that stores users in repo
+// and registers its counters in reg.
+func NewUserServiceWithRepository(repo UserRepository, bookOperationService BookOperationsService, logger *slog.Logger, reg *metrics.Registry) *UserService {
 	return &UserService{
-		users: users,
-		bs:    bookOperationService,
+		users:   repo,
+		bs:      bookOperationService,
+		logger:  logger,
+		metrics: newServiceMetrics(reg),
 	}
 }
 
 // Get returns a given user or error if none exists.
-func (us *UserService) Get(id string) (*User, []Book, error) {
-	u, ok := us.users[id]
-	if !ok {
-		return nil, nil, errors.New("user does not exist")
+func (us *UserService) Get(ctx context.Context, id string) (*User, []Book, error) {
+	u, ok := us.users.Get(id)
+	if !ok || u.DeletedAt != nil {
+		return nil, nil, ErrUserNotFound
 	}
-	books := us.bs.ListByUser(id)
-	fmt.Println(len(books))
+	books := us.bs.ListByUser(ctx, id)
+	us.logger.DebugContext(ctx, "user found", "user_id", id, "books", len(books))
 
 	return &u, books, nil
 }
 
 // Exists returns whether a given user exists and returns an error if none found.
-func (us *UserService) Exists(id string) error {
-	if _, ok := us.users[id]; !ok {
-		fmt.Printf("DOESN:T EXIST")
-		return errors.New("no user found")
+func (us *UserService) Exists(ctx context.Context, id string) error {
+	if u, ok := us.users.Get(id); !ok || u.DeletedAt != nil {
+		us.logger.DebugContext(ctx, "user does not exist", "user_id", id)
+		return NewError(ErrNotFound, "user_not_found", "no user found")
 	}
 	return nil
 }
 
-// Upsert creates or updates a new user.
-func (us *UserService) Upsert(u User) (User, error) {
-	u.ID = uuid.NewString()
-	us.users[u.ID] = u
+// ListByCountry returns the users of a given country, ignoring case.
+func (us *UserService) ListByCountry(ctx context.Context, country string) []User {
+	var items = make([]User, 0)
+	for _, u := range us.users.List() {
+		if strings.EqualFold(u.Country, country) && u.DeletedAt == nil {
+			items = append(items, u)
+		}
+	}
+	return items
+}
+
+// Upsert creates a new user or updates the user with the same ID, keeping the ID.
+func (us *UserService) Upsert(ctx context.Context, u User) (User, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	existing, ok := us.users.Get(u.ID)
+	if !ok {
+		u.ID = uuid.NewString()
+	} else if existing.DeletedAt != nil {
+		return User{}, ErrUserNotFound
+	}
+	u.DeletedAt = nil
+	if err := us.users.Save(u); err != nil {
+		return User{}, err
+	}
+	if !ok {
+		us.logger.InfoContext(ctx, "user registered", "user_id", u.ID)
+	}
+	us.metrics.upserts.Inc("user")
 
 	return u, nil
 }
+
+// Update atomically applies change to the user with the given ID. Users can
+// only update themselves and the ID of a user never changes.
+func (us *UserService) Update(ctx context.Context, id, userID string, change func(u *User) error) (*User, error) {
+	return us.update(id, userID, func(u *User) error {
+		edited := *u
+		if err := change(&edited); err != nil {
+			return err
+		}
+		u.Name, u.Address, u.PostCode, u.Country = edited.Name, edited.Address, edited.PostCode, edited.Country
+		return nil
+	})
+}
+
+// Delete soft deletes the user with the given ID. Users can only delete
+// themselves, once they have deleted or swapped away all of their books.
+func (us *UserService) Delete(ctx context.Context, id, userID string) error {
+	_, err := us.update(id, userID, func(u *User) error {
+		if len(us.bs.ListByUser(ctx, id)) > 0 {
+			return ErrUserHasBooks
+		}
+		now := time.Now().UTC()
+		u.DeletedAt = &now
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	us.logger.InfoContext(ctx, "user deleted", "user_id", id)
+	return nil
+}
+
+// update atomically applies change to a user acting on themselves and saves the result.
+func (us *UserService) update(id, userID string, change func(u *User) error) (*User, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	u, ok := us.users.Get(id)
+	if !ok || u.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	if id != userID {
+		return nil, fmt.Errorf("%w: users can only change themselves", ErrNotAllowed)
+	}
+	if err := change(&u); err != nil {
+		return nil, err
+	}
+	if err := us.users.Save(u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
@@ -1,12 +1,14 @@
 package db_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
 	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/mocks"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -27,14 +29,14 @@ func TestGetUser(t *testing.T) {
 			Name:    "Book One",
 			Author:  "Author One",
 			OwnerID: userId,
-			Status:  db.Available.String(),
+			Status:  db.Available,
 		}
 		bookOperationService := mocks.NewBookOperationsService(t)
-		bookOperationService.On("ListByUser", userId).Return([]db.Book{book})
+		bookOperationService.On("ListByUser", mock.Anything, userId).Return([]db.Book{book})
 		userService := db.NewUserService(users, bookOperationService)
 
 		// Act
-		user, books, error := userService.Get(userId)
+		user, books, error := userService.Get(context.Background(), userId)
 
 		// Assert
 		require.Nil(t, error)
@@ -60,7 +62,7 @@ func TestGetUser(t *testing.T) {
 		userService := db.NewUserService(initial, bookOperationService)
 
 		// Act
-		user, books, error := userService.Get(userId)
+		user, books, error := userService.Get(context.Background(), userId)
 
 		// Assert
 		require.Nil(t, user)
@@ -82,7 +84,7 @@ func TestExists(t *testing.T) {
 		us := db.NewUserService([]db.User{eu}, bs)
 
 		// Act
-		error := us.Exists(eu.ID)
+		error := us.Exists(context.Background(), eu.ID)
 
 		// Assert
 		require.Nil(t, error)
@@ -93,7 +95,7 @@ func TestExists(t *testing.T) {
 		us := db.NewUserService([]db.User{eu}, bs)
 
 		// Act
-		error := us.Exists(uuid.New().String())
+		error := us.Exists(context.Background(), uuid.New().String())
 
 		// Assert
 		require.NotNil(t, error)
@@ -105,7 +107,7 @@ func TestExists(t *testing.T) {
 		us := db.NewUserService([]db.User{}, bs)
 
 		// Act
-		error := us.Exists(eu.ID)
+		error := us.Exists(context.Background(), eu.ID)
 
 		// Assert
 		require.NotNil(t, error)
@@ -128,7 +130,7 @@ func TestUpsertUser(t *testing.T) {
 			Name: "Updated user",
 		}
 		// Act
-		user, error := us.Upsert(updatedUser)
+		user, error := us.Upsert(context.Background(), updatedUser)
 
 		// Assert
 		require.Nil(t, error)
@@ -136,3 +138,134 @@ func TestUpsertUser(t *testing.T) {
 		assert.Equal(t, updatedUser.Name, user.Name)
 	})
 }
+
+func TestListByCountry(t *testing.T) {
+	// Arrange
+	users := []db.User{
+		{ID: uuid.NewString(), Name: "User One", Country: "Macedonia"},
+		{ID: uuid.NewString(), Name: "User Two", Country: "Germany"},
+	}
+	userService := db.NewUserService(users, nil)
+
+	// Act
+	found := userService.ListByCountry(context.Background(), "macedonia")
+
+	// Assert
+	assert.Equal(t, users[:1], found)
+	assert.Empty(t, userService.ListByCountry(context.Background(), "France"))
+}
+
+func TestUpsertUserKeepsID(t *testing.T) {
+	// Arrange
+	eu := db.User{ID: uuid.New().String(), Name: "Existing user"}
+	bs := mocks.NewBookOperationsService(t)
+	bs.On("ListByUser", mock.Anything, eu.ID).Return([]db.Book{})
+	us := db.NewUserService([]db.User{eu}, bs)
+
+	// Act
+	user, err := us.Upsert(context.Background(), db.User{ID: eu.ID, Name: "Updated user"})
+
+	// Assert
+	require.Nil(t, err)
+	assert.Equal(t, eu.ID, user.ID)
+	stored, _, err := us.Get(context.Background(), eu.ID)
+	require.Nil(t, err)
+	assert.Equal(t, "Updated user", stored.Name)
+}
+
+func TestUpdateUser(t *testing.T) {
+	eu := db.User{ID: uuid.New().String(), Name: "Existing user", Country: "Macedonia"}
+
+	t.Run("themselves", func(t *testing.T) {
+		// Arrange
+		bs := mocks.NewBookOperationsService(t)
+		bs.On("ListByUser", mock.Anything, eu.ID).Return([]db.Book{})
+		us := db.NewUserService([]db.User{eu}, bs)
+
+		// Act
+		user, err := us.Update(context.Background(), eu.ID, eu.ID, func(u *db.User) error {
+			u.ID = "changed"
+			u.Country = "Germany"
+			return nil
+		})
+
+		// Assert
+		require.Nil(t, err)
+		assert.Equal(t, eu.ID, user.ID)
+		assert.Equal(t, eu.Name, user.Name)
+		assert.Equal(t, "Germany", user.Country)
+		stored, _, err := us.Get(context.Background(), eu.ID)
+		require.Nil(t, err)
+		assert.Equal(t, *user, *stored)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		// Arrange
+		us := db.NewUserService([]db.User{eu}, nil)
+
+		// Act
+		_, err := us.Update(context.Background(), eu.ID, uuid.New().String(), func(u *db.User) error {
+			u.Name = "Changed"
+			return nil
+		})
+
+		// Assert
+		assert.ErrorIs(t, err, db.ErrNotAllowed)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		// Arrange
+		us := db.NewUserService(nil, nil)
+
+		// Act
+		_, err := us.Update(context.Background(), "not-found", "not-found", func(u *db.User) error { return nil })
+
+		// Assert
+		assert.ErrorIs(t, err, db.ErrUserNotFound)
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	eu := db.User{ID: uuid.New().String(), Name: "Existing user"}
+
+	t.Run("without books", func(t *testing.T) {
+		// Arrange
+		bs := mocks.NewBookOperationsService(t)
+		bs.On("ListByUser", mock.Anything, eu.ID).Return([]db.Book{})
+		us := db.NewUserService([]db.User{eu}, bs)
+
+		// Act
+		err := us.Delete(context.Background(), eu.ID, eu.ID)
+
+		// Assert
+		require.Nil(t, err)
+		_, _, err = us.Get(context.Background(), eu.ID)
+		assert.ErrorIs(t, err, db.ErrUserNotFound)
+		assert.NotNil(t, us.Exists(context.Background(), eu.ID))
+	})
+
+	t.Run("with books", func(t *testing.T) {
+		// Arrange
+		bs := mocks.NewBookOperationsService(t)
+		bs.On("ListByUser", mock.Anything, eu.ID).Return([]db.Book{{ID: uuid.New().String(), OwnerID: eu.ID}})
+		us := db.NewUserService([]db.User{eu}, bs)
+
+		// Act
+		err := us.Delete(context.Background(), eu.ID, eu.ID)
+
+		// Assert
+		assert.ErrorIs(t, err, db.ErrUserHasBooks)
+		assert.Nil(t, us.Exists(context.Background(), eu.ID))
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		// Arrange
+		us := db.NewUserService([]db.User{eu}, nil)
+
+		// Act
+		err := us.Delete(context.Background(), eu.ID, uuid.New().String())
+
+		// Assert
+		assert.ErrorIs(t, err, db.ErrNotAllowed)
+	})
+}
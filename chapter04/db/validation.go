@@ -0,0 +1,161 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes why the value of a field is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists the invalid fields of a user or book.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Is reports that validation errors are of the kind ErrInvalid.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+func (e *ValidationError) Error() string {
+	var msgs []string
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Field+" "+fe.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, ", ")
+}
+
+// rule checks the value of a field of v. It returns a message if the value
+// is invalid and is passed the parameter after = in the validate tag.
+type rule func(v reflect.Value, field reflect.Value, param string) string
+
+// rules are the rules that may be used in validate struct tags, such as
+// `validate:"required,max=100"`. Rules run in order and stop at the first
+// message.
+var rules = map[string]rule{
+	"required": func(_, field reflect.Value, _ string) string {
+		if strings.TrimSpace(field.String()) == "" {
+			return "is required"
+		}
+		return ""
+	},
+	"max": func(_, field reflect.Value, param string) string {
+		n, _ := strconv.Atoi(param)
+		if utf8.RuneCountInString(field.String()) > n {
+			return fmt.Sprintf("must be at most %d characters", n)
+		}
+		return ""
+	},
+	"country": func(_, field reflect.Value, _ string) string {
+		if !countryCodes[field.String()] {
+			return "must be an ISO 3166-1 alpha-2 country code"
+		}
+		return ""
+	},
+	// postcode checks the format of a post code for the country code in
+	// the field named by its parameter.
+	"postcode": func(v, field reflect.Value, param string) string {
+		country := v.FieldByName(param).String()
+		if !countryCodes[country] {
+			return ""
+		}
+		format, ok := postCodeFormats[country]
+		if !ok {
+			format = defaultPostCodeFormat
+		}
+		if !format.MatchString(field.String()) {
+			return "is not a valid post code for " + country
+		}
+		return ""
+	},
+}
+
+// validate checks the fields of the struct v against their validate tags.
+func validate(v any) error {
+	val := reflect.ValueOf(v)
+	typ := val.Type()
+	var errs []FieldError
+	for i := 0; i < typ.NumField(); i++ {
+		tag := typ.Field(i).Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		for _, r := range strings.Split(tag, ",") {
+			name, param, _ := strings.Cut(r, "=")
+			check, ok := rules[name]
+			if !ok {
+				panic(fmt.Sprintf("unknown validation rule %s on %s.%s", name, typ.Name(), typ.Field(i).Name))
+			}
+			if msg := check(val, val.Field(i), param); msg != "" {
+				errs = append(errs, FieldError{Field: jsonName(typ.Field(i)), Message: msg})
+				break
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func jsonName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" {
+		return name
+	}
+	return f.Name
+}
+
+// defaultPostCodeFormat accepts the post codes of the countries
+// missing from postCodeFormats.
+var defaultPostCodeFormat = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 -]{1,9}$`)
+
+var postCodeFormats = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"BG": regexp.MustCompile(`^\d{4}$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"GR": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"MK": regexp.MustCompile(`^\d{4}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"RS": regexp.MustCompile(`^\d{5}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// countryCodes are the ISO 3166-1 alpha-2 country codes.
+var countryCodes = func() map[string]bool {
+	codes := make(map[string]bool)
+	for _, c := range strings.Fields(`
+		AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE BF BG BH BI BJ BL
+		BM BN BO BQ BR BS BT BV BW BY BZ CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV
+		CW CX CY CZ DE DJ DK DM DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO FR GA GB GD
+		GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU ID IE IL IM
+		IN IO IQ IR IS IT JE JM JO JP KE KG KH KI KM KN KP KR KW KY KZ LA LB LC LI LK
+		LR LS LT LU LV LY MA MC MD ME MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW
+		MX MY MZ NA NC NE NF NG NI NL NO NP NR NU NZ OM PA PE PF PG PH PK PL PM PN PR
+		PS PT PW PY QA RE RO RS RU RW SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS
+		ST SV SX SY SZ TC TD TF TG TH TJ TK TL TM TN TO TR TT TV TW TZ UA UG UM US UY
+		UZ VA VC VE VG VI VN VU WF WS YE YT ZA ZM ZW`) {
+		codes[c] = true
+	}
+	return codes
+}()
@@ -0,0 +1,109 @@
+package db_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateUser(t *testing.T) {
+	valid := db.User{Name: "Reader", Address: "Partizanska 1", PostCode: "1000", Country: "MK"}
+
+	tests := map[string]struct {
+		change func(u *db.User)
+		want   []db.FieldError
+	}{
+		"valid": {
+			change: func(u *db.User) {},
+		},
+		"us zip+4": {
+			change: func(u *db.User) { u.PostCode, u.Country = "10001-1234", "US" },
+		},
+		"country without post code format": {
+			change: func(u *db.User) { u.PostCode, u.Country = "LV-1050", "LV" },
+		},
+		"blank name": {
+			change: func(u *db.User) { u.Name = "  " },
+			want:   []db.FieldError{{Field: "name", Message: "is required"}},
+		},
+		"long address": {
+			change: func(u *db.User) { u.Address = strings.Repeat("a", 201) },
+			want:   []db.FieldError{{Field: "address", Message: "must be at most 200 characters"}},
+		},
+		"post code of other country": {
+			change: func(u *db.User) { u.PostCode = "SW1A 1AA" },
+			want:   []db.FieldError{{Field: "post_code", Message: "is not a valid post code for MK"}},
+		},
+		"country name": {
+			change: func(u *db.User) { u.Country = "Macedonia" },
+			want:   []db.FieldError{{Field: "country", Message: "must be an ISO 3166-1 alpha-2 country code"}},
+		},
+		"empty": {
+			change: func(u *db.User) { *u = db.User{} },
+			want: []db.FieldError{
+				{Field: "name", Message: "is required"},
+				{Field: "address", Message: "is required"},
+				{Field: "post_code", Message: "is required"},
+				{Field: "country", Message: "is required"},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			u := valid
+			tc.change(&u)
+
+			// Act
+			err := u.Validate()
+
+			// Assert
+			if tc.want == nil {
+				assert.Nil(t, err)
+				return
+			}
+			var verr *db.ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tc.want, verr.Errors)
+		})
+	}
+}
+
+func TestValidateBook(t *testing.T) {
+	tests := map[string]struct {
+		book db.Book
+		want []db.FieldError
+	}{
+		"valid": {
+			book: db.Book{Name: "Dune", Author: "Frank Herbert"},
+		},
+		"no title": {
+			book: db.Book{Author: "Frank Herbert"},
+			want: []db.FieldError{{Field: "name", Message: "is required"}},
+		},
+		"long author": {
+			book: db.Book{Name: "Dune", Author: strings.Repeat("ä", 101)},
+			want: []db.FieldError{{Field: "author", Message: "must be at most 100 characters"}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			err := tc.book.Validate()
+
+			// Assert
+			if tc.want == nil {
+				assert.Nil(t, err)
+				return
+			}
+			var verr *db.ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tc.want, verr.Errors)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/gorilla/mux"
+)
+
+// ListOutbox is invoked by HTTP GET /admin/outbox?status={status}.
+// It lists every event of the outbox, or those with the given status,
+// such as DEAD for the failed deliveries.
+func (h *Handler) ListOutbox(r *http.Request) (int, *Response, error) {
+	var statuses []db.EventStatus
+	if name := r.URL.Query().Get("status"); name != "" {
+		s, err := db.ParseEventStatus(name)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%w: %v", db.ErrInvalidQuery, err)
+		}
+		statuses = append(statuses, s)
+	}
+	return http.StatusOK, &Response{
+		Events: h.bs.Outbox().List(statuses...),
+	}, nil
+}
+
+// ReplayOutbox is invoked by HTTP POST /admin/outbox/{id}/replay.
+func (h *Handler) ReplayOutbox(r *http.Request) (int, *Response, error) {
+	event, err := h.bs.Outbox().Replay(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, &Response{
+		Event: event,
+	}, nil
+}
+
+// PasswordRequest is the body of PUT /admin/users/{id}/password.
+type PasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// SetUserPassword is invoked by HTTP PUT /admin/users/{id}/password. It lets an
+// administrator set the password of any user, such as the users seeded from
+// users.json, who have no password to log in with until then.
+func (h *Handler) SetUserPassword(r *http.Request) (int, *Response, error) {
+	userID := mux.Vars(r)["id"]
+	var req PasswordRequest
+	body, err := readRequestBody(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := decodeBody(body, &req); err != nil {
+		return 0, nil, err
+	}
+
+	if err := h.us.Exists(r.Context(), userID); err != nil {
+		return 0, nil, err
+	}
+	if err := h.auth.SetPassword(userID, req.Password); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, &Response{
+		Message: "password set",
+	}, nil
+}
@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/handlers"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxEndpoints(t *testing.T) {
+	// Arrange
+	user := db.User{ID: uuid.NewString(), Name: "Collector"}
+	owner := db.User{ID: uuid.NewString(), Name: "Owner"}
+	book := db.Book{ID: uuid.NewString(), Name: "Posted", OwnerID: owner.ID, Status: db.Available}
+	srv := newServer(t, []db.Book{book}, []db.User{user, owner})
+	status, resp := post(t, fmt.Sprintf("%s/books/%s?user=%s", srv.URL, book.ID, user.ID), nil)
+	require.Equal(t, http.StatusCreated, status)
+	for _, action := range []string{"accept", "ship"} {
+		status, _ = post(t, fmt.Sprintf("%s/swaps/%s/%s?user=%s", srv.URL, resp.Swap.ID, action, owner.ID), nil)
+		require.Equal(t, http.StatusOK, status, action)
+	}
+
+	// Act
+	var events []db.OutboxEvent
+	require.Eventually(t, func() bool {
+		status, resp := get(t, srv.URL+"/admin/outbox?status=DELIVERED&user="+testAdmin.ID)
+		events = resp.Events
+		return status == http.StatusOK && len(events) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Assert
+	assert.Equal(t, book.ID, events[0].Book.ID)
+	assert.NotEmpty(t, events[0].OrderID)
+
+	status, resp = get(t, srv.URL+"/admin/outbox?status=DEAD&user="+testAdmin.ID)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, resp.Events)
+
+	status, _ = get(t, srv.URL+"/admin/outbox?status=LOST&user="+testAdmin.ID)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = post(t, fmt.Sprintf("%s/admin/outbox/%s/replay?user=%s", srv.URL, events[0].ID, testAdmin.ID), nil)
+	assert.Equal(t, http.StatusConflict, status)
+	status, _ = post(t, srv.URL+"/admin/outbox/not-found/replay?user="+testAdmin.ID, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestOutboxEndpointsRequireAdmin(t *testing.T) {
+	// Arrange
+	user := db.User{ID: uuid.NewString(), Name: "Curious"}
+	srv := newServer(t, nil, []db.User{user})
+
+	tests := map[string]struct {
+		query      string
+		wantStatus int
+		wantCode   string
+	}{
+		"anonymous caller": {
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "authentication_required",
+		},
+		"caller who is not an administrator": {
+			query:      "?user=" + user.ID,
+			wantStatus: http.StatusForbidden,
+			wantCode:   "not_allowed",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, r := range []struct{ method, path string }{
+				{http.MethodGet, "/v1/admin/outbox"},
+				{http.MethodPost, "/v1/admin/outbox/some-event/replay"},
+				{http.MethodPut, "/v1/admin/users/" + user.ID + "/password"},
+				{http.MethodGet, "/admin/outbox"},
+			} {
+				// Act
+				status, p := sendProblem(t, r.method, srv.URL+r.path+tc.query, nil)
+
+				// Assert
+				assert.Equal(t, tc.wantStatus, status, r.path)
+				assert.Equal(t, tc.wantCode, p.Code, r.path)
+			}
+		})
+	}
+}
+
+func TestSetUserPassword(t *testing.T) {
+	// Arrange
+	seeded := db.User{ID: uuid.NewString(), Name: "Seeded"}
+	srv := newServer(t, nil, []db.User{seeded})
+	login := handlers.LoginRequest{UserID: seeded.ID, Password: "correct horse"}
+	status, _ := post(t, srv.URL+"/v1/login", login)
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	// Act
+	status, resp := send(t, http.MethodPut, fmt.Sprintf("%s/v1/admin/users/%s/password?user=%s", srv.URL, seeded.ID, testAdmin.ID),
+		handlers.PasswordRequest{Password: login.Password})
+
+	// Assert
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "password set", resp.Message)
+	status, resp = post(t, srv.URL+"/v1/login", login)
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, resp.Token)
+
+	status, _ = send(t, http.MethodPut, fmt.Sprintf("%s/v1/admin/users/%s/password?user=%s", srv.URL, seeded.ID, testAdmin.ID),
+		handlers.PasswordRequest{Password: "short"})
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	status, _ = send(t, http.MethodPut, fmt.Sprintf("%s/v1/admin/users/%s/password?user=%s", srv.URL, uuid.NewString(), testAdmin.ID),
+		handlers.PasswordRequest{Password: login.Password})
+	assert.Equal(t, http.StatusNotFound, status)
+}
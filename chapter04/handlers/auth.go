@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+)
+
+// contextKey is the type of the request context keys of this package.
+type contextKey int
+
+// callerKey is the context key of the ID of the authenticated user.
+const callerKey contextKey = iota
+
+var errAuthenticationRequired = db.NewError(db.ErrUnauthenticated, "authentication_required", "authentication required")
+
+// LoginRequest is the body of POST /login.
+type LoginRequest struct {
+	UserID   string `json:"user_id"`
+	Password string `json:"password"`
+}
+
+// Login is invoked by HTTP POST /login and answers with a bearer token.
+func (h *Handler) Login(r *http.Request) (int, *Response, error) {
+	var req LoginRequest
+	body, err := readRequestBody(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := decodeBody(body, &req); err != nil {
+		return 0, nil, err
+	}
+
+	if err := h.us.Exists(r.Context(), req.UserID); err != nil {
+		return 0, nil, db.ErrInvalidCredentials
+	}
+	token, err := h.auth.Login(req.UserID, req.Password)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, &Response{
+		Token: token,
+	}, nil
+}
+
+// authenticate resolves the caller from the bearer token in the Authorization
+// header. Requests without a token carry on anonymously, while requests with
+// an invalid token, or the token of a deleted user, are rejected.
+func (h *Handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			writeProblem(w, r, fmt.Errorf("%w: authorization must be a bearer token", db.ErrInvalidToken))
+			return
+		}
+		userID, err := h.auth.Verify(token)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		if err := h.us.Exists(r.Context(), userID); err != nil {
+			writeProblem(w, r, fmt.Errorf("%w: user no longer exists", db.ErrInvalidToken))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callerKey, userID)))
+	})
+}
+
+// caller returns the ID of the authenticated user making the request. It fails
+// when the request is anonymous or when the ?user= query parameter, if any,
+// names another user.
+func caller(r *http.Request) (string, error) {
+	userID, _ := r.Context().Value(callerKey).(string)
+	if userID == "" {
+		return "", errAuthenticationRequired
+	}
+	if user := r.URL.Query().Get("user"); user != "" && user != userID {
+		return "", fmt.Errorf("%w: cannot act on behalf of another user", db.ErrNotAllowed)
+	}
+	return userID, nil
+}
+
+// requireCaller rejects anonymous requests.
+func (h *Handler) requireCaller(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := caller(r); err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAdmin rejects the requests of anonymous callers and of callers
+// that are not administrators.
+func (h *Handler) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := caller(r)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		if !h.auth.IsAdmin(userID) {
+			writeProblem(w, r, fmt.Errorf("%w: administrators only", db.ErrNotAllowed))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/handlers"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// do sends a request with the given bearer token, if any.
+func do(t *testing.T, method, url, token string) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	require.Nil(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	// Arrange
+	book := db.Book{ID: uuid.NewString(), Name: "Wanted", OwnerID: uuid.NewString(), Status: db.Available}
+	srv := newServer(t, []db.Book{book}, nil)
+	status, resp := post(t, srv.URL+"/users", handlers.UserRequest{
+		User:     db.User{Name: "Reader", Address: "Partizanska 1", PostCode: "1000", Country: "MK"},
+		Password: "correct horse",
+	})
+	require.Equal(t, http.StatusOK, status)
+	require.NotEmpty(t, resp.Token)
+	userID := resp.User.ID
+
+	// Act
+	status, resp = post(t, srv.URL+"/login", handlers.LoginRequest{UserID: userID, Password: "correct horse"})
+
+	// Assert
+	require.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, resp.Token)
+	status, _ = post(t, srv.URL+"/login", handlers.LoginRequest{UserID: userID, Password: "wrong password"})
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = post(t, srv.URL+"/login", handlers.LoginRequest{UserID: uuid.NewString(), Password: "correct horse"})
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// Act
+	status = do(t, http.MethodPost, fmt.Sprintf("%s/books/%s", srv.URL, book.ID), resp.Token)
+
+	// Assert
+	require.Equal(t, http.StatusCreated, status)
+	_, resp = get(t, srv.URL+"/books/"+book.ID)
+	require.Len(t, resp.Books, 1)
+	assert.Equal(t, db.Requested, resp.Books[0].Status)
+}
+
+func TestRegisterWithoutPassword(t *testing.T) {
+	// Arrange
+	srv := newServer(t, nil, nil)
+
+	// Act
+	status, problem := sendProblem(t, http.MethodPost, srv.URL+"/users", db.User{Name: "Reader", Address: "Partizanska 1", PostCode: "1000", Country: "MK"})
+
+	// Assert
+	require.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, "password", problem.Errors[0].Field)
+}
+
+func TestAuthorization(t *testing.T) {
+	// Arrange
+	owner := db.User{ID: uuid.NewString(), Name: "Owner", Address: "Partizanska 1", PostCode: "1000", Country: "MK"}
+	thief := db.User{ID: uuid.NewString(), Name: "Thief"}
+	book := db.Book{ID: uuid.NewString(), Name: "Guarded", OwnerID: owner.ID, Status: db.Available}
+	srv := newServer(t, []db.Book{book}, []db.User{owner, thief})
+	thiefToken, err := testAuth.Token(thief.ID)
+	require.Nil(t, err)
+
+	tests := map[string]struct {
+		method string
+		url    string
+		token  string
+		want   int
+	}{
+		"anonymous swap": {
+			method: http.MethodPost,
+			url:    fmt.Sprintf("%s/books/%s?user=%s", srv.URL, book.ID, thief.ID),
+			want:   http.StatusUnauthorized,
+		},
+		"swap on behalf of another user": {
+			method: http.MethodPost,
+			url:    fmt.Sprintf("%s/books/%s?user=%s", srv.URL, book.ID, owner.ID),
+			token:  thiefToken,
+			want:   http.StatusForbidden,
+		},
+		"anonymous swap request": {
+			method: http.MethodPost,
+			url:    srv.URL + "/swaps",
+			want:   http.StatusUnauthorized,
+		},
+		"invalid token": {
+			method: http.MethodGet,
+			url:    srv.URL + "/swaps",
+			token:  "not-a-token",
+			want:   http.StatusUnauthorized,
+		},
+		"token of unknown user": {
+			method: http.MethodGet,
+			url:    srv.URL + "/books",
+			token:  func() string { token, _ := testAuth.Token(uuid.NewString()); return token }(),
+			want:   http.StatusUnauthorized,
+		},
+		"anonymous listing": {
+			method: http.MethodGet,
+			url:    srv.URL + "/books",
+			want:   http.StatusOK,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			status := do(t, tc.method, tc.url, tc.token)
+
+			// Assert
+			assert.Equal(t, tc.want, status)
+		})
+	}
+
+	status, _ := post(t, srv.URL+"/books?user="+thief.ID, db.Book{ID: book.ID, Name: "Stolen", Author: "Thief", OwnerID: owner.ID})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = post(t, srv.URL+"/users?user="+thief.ID, db.User{ID: owner.ID, Name: "Hijacked", Address: "Partizanska 1", PostCode: "1000", Country: "MK"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, resp := get(t, srv.URL+"/books/"+book.ID)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Guarded", resp.Books[0].Name)
+	assert.Equal(t, db.Available, resp.Books[0].Status)
+}
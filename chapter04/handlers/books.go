@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/gorilla/mux"
+)
+
+// GetBook is invoked by HTTP GET /books/{id}.
+func (h *Handler) GetBook(r *http.Request) (int, *Response, error) {
+	book, err := h.bs.Get(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, &Response{
+		Books: []db.Book{*book},
+	}, nil
+}
+
+// editBook returns the handler of HTTP PUT and PATCH /books/{id}, which
+// the owner of the book calls with their bearer token. PUT replaces the
+// name and author of the book with those in the body, while PATCH only
+// changes the fields present in the body.
+func (h *Handler) editBook(replace bool) handlerFunc {
+	return func(r *http.Request) (int, *Response, error) {
+		bookID := mux.Vars(r)["id"]
+		userID, err := caller(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		body, err := readRequestBody(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		var edit db.Book
+		if err := decodeBody(body, &edit); err != nil {
+			return 0, nil, err
+		}
+
+		book, err := h.bs.Update(r.Context(), bookID, userID, func(b *db.Book) error {
+			if replace {
+				*b = edit
+			} else if err := json.Unmarshal(body, b); err != nil {
+				return err
+			}
+			return b.Validate()
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, &Response{
+			Books: []db.Book{*book},
+		}, nil
+	}
+}
+
+// DeleteBook is invoked by HTTP DELETE /books/{id} by the owner of the book.
+func (h *Handler) DeleteBook(r *http.Request) (int, *Response, error) {
+	bookID := mux.Vars(r)["id"]
+	userID, err := caller(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := h.bs.Delete(r.Context(), bookID, userID); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, &Response{
+		Message: "book deleted",
+	}, nil
+}
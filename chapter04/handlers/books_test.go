@@ -0,0 +1,111 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookCRUD(t *testing.T) {
+	// Arrange
+	owner := db.User{ID: uuid.NewString(), Name: "Owner"}
+	other := db.User{ID: uuid.NewString(), Name: "Other"}
+	srv := newServer(t, nil, []db.User{owner, other})
+	status, resp := post(t, srv.URL+"/books?user="+owner.ID, db.Book{Name: "Dune", Author: "Herbert", OwnerID: owner.ID})
+	require.Equal(t, http.StatusOK, status)
+	book := resp.Books[0]
+	url := fmt.Sprintf("%s/books/%s?user=", srv.URL, book.ID)
+
+	// Act
+	status, resp = send(t, http.MethodPatch, url+owner.ID, map[string]string{"name": "Dune Messiah"})
+
+	// Assert
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, book.ID, resp.Books[0].ID)
+	assert.Equal(t, "Dune Messiah", resp.Books[0].Name)
+	assert.Equal(t, "Herbert", resp.Books[0].Author)
+
+	// Act
+	status, resp = send(t, http.MethodPut, url+owner.ID, db.Book{Name: "Emma", Author: "Austen"})
+
+	// Assert
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Emma", resp.Books[0].Name)
+	assert.Equal(t, owner.ID, resp.Books[0].OwnerID)
+	status, resp = get(t, srv.URL+"/books/"+book.ID)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Austen", resp.Books[0].Author)
+
+	// Act
+	status, _ = send(t, http.MethodPut, url+other.ID, db.Book{Name: "Stolen", Author: "Herbert"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = post(t, srv.URL+"/books?user="+other.ID, db.Book{ID: book.ID, Name: "Stolen", Author: "Herbert", OwnerID: other.ID})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = send(t, http.MethodDelete, url+other.ID, nil)
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = send(t, http.MethodDelete, url+owner.ID, nil)
+
+	// Assert
+	require.Equal(t, http.StatusOK, status)
+	status, _ = get(t, srv.URL+"/books/"+book.ID)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, resp = get(t, srv.URL+"/books")
+	require.Equal(t, http.StatusOK, status)
+	assert.Empty(t, resp.Books)
+	status, _ = send(t, http.MethodPatch, url+owner.ID, map[string]string{"name": "Undeleted"})
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestDeleteBookKeepsSwaps(t *testing.T) {
+	// Arrange
+	owner := db.User{ID: uuid.NewString(), Name: "Owner"}
+	requester := db.User{ID: uuid.NewString(), Name: "Requester"}
+	book := db.Book{ID: uuid.NewString(), Name: "Swappable", OwnerID: owner.ID, Status: db.Available}
+	srv := newServer(t, []db.Book{book}, []db.User{owner, requester})
+	status, resp := post(t, srv.URL+"/swaps?user="+requester.ID, map[string]string{"book_id": book.ID})
+	require.Equal(t, http.StatusCreated, status)
+	swapID := resp.Swap.ID
+	url := fmt.Sprintf("%s/books/%s?user=%s", srv.URL, book.ID, owner.ID)
+
+	// Act
+	status, _ = send(t, http.MethodDelete, url, nil)
+	require.Equal(t, http.StatusConflict, status)
+	status, _ = post(t, fmt.Sprintf("%s/swaps/%s/reject?user=%s", srv.URL, swapID, owner.ID), nil)
+	require.Equal(t, http.StatusOK, status)
+	status, _ = send(t, http.MethodDelete, url, nil)
+
+	// Assert
+	require.Equal(t, http.StatusOK, status)
+	status, resp = get(t, fmt.Sprintf("%s/swaps/%s?user=%s", srv.URL, swapID, requester.ID))
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, book.ID, resp.Swap.BookID)
+}
+
+func TestBookValidation(t *testing.T) {
+	// Arrange
+	owner := db.User{ID: uuid.NewString(), Name: "Owner"}
+	book := db.Book{ID: uuid.NewString(), Name: "Dune", Author: "Herbert", OwnerID: owner.ID}
+	srv := newServer(t, []db.Book{book}, []db.User{owner})
+
+	// Act
+	status, problem := sendProblem(t, http.MethodPost, srv.URL+"/books?user="+owner.ID, db.Book{OwnerID: owner.ID})
+
+	// Assert
+	require.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, []db.FieldError{
+		{Field: "name", Message: "is required"},
+		{Field: "author", Message: "is required"},
+	}, problem.Errors)
+
+	// Act
+	status, problem = sendProblem(t, http.MethodPut, fmt.Sprintf("%s/books/%s?user=%s", srv.URL, book.ID, owner.ID), db.Book{Name: "Dune"})
+
+	// Assert
+	require.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, []db.FieldError{{Field: "author", Message: "is required"}}, problem.Errors)
+}
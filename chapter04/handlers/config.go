@@ -3,19 +3,104 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
 	"github.com/gorilla/mux"
 )
 
-// ConfigureServer configures the routes of this server and binds handler functions to them
+// apiVersion prefixes the paths of the current version of the API.
+const apiVersion = "/v1"
+
+// route binds a handler to a method and path, and documents it in the
+// OpenAPI document of the server.
+type route struct {
+	method  string
+	path    string
+	summary string
+	// auth is true when the route requires a bearer token, and admin when
+	// it requires the token of an administrator. ConfigureServer rejects
+	// the requests without one, so the document and the server agree.
+	auth  bool
+	admin bool
+	// body is the request body, nil when the route takes none.
+	body any
+	// query lists the query parameters of the route.
+	query []string
+	// status is the status of a successful response, 200 when zero.
+	status  int
+	handler http.Handler
+}
+
+// routes returns the routes of the API, relative to its version prefix.
+func (handler *Handler) routes() []route {
+	return []route{
+		{method: "GET", path: "/", summary: "Welcome message and every book", handler: handlerFunc(handler.Index)},
+		{method: "GET", path: "/books", summary: "Search, filter and page books", query: []string{"q", "author", "owner", "country", "status", "sort", "limit", "page_token"}, handler: handlerFunc(handler.ListBooks)},
+		{method: "POST", path: "/login", summary: "Exchange a password for a bearer token", body: LoginRequest{}, handler: handlerFunc(handler.Login)},
+		{method: "POST", path: "/users", summary: "Register a user or update the caller", body: UserRequest{}, handler: handlerFunc(handler.UserUpsert)},
+		{method: "GET", path: "/users/{id}", summary: "Get a user and their books", handler: handlerFunc(handler.ListUserByID)},
+		{method: "PUT", path: "/users/{id}", summary: "Replace the details of the caller", auth: true, body: db.User{}, handler: handler.editUser(true)},
+		{method: "PATCH", path: "/users/{id}", summary: "Change some details of the caller", auth: true, body: db.User{}, handler: handler.editUser(false)},
+		{method: "DELETE", path: "/users/{id}", summary: "Delete the caller", auth: true, handler: handlerFunc(handler.DeleteUser)},
+		{method: "POST", path: "/books/{id}", summary: "Request a swap of a book, like POST /swaps", auth: true, status: http.StatusCreated, handler: handlerFunc(handler.SwapBook)},
+		{method: "POST", path: "/books", summary: "Create or update a book of the caller", auth: true, body: db.Book{}, handler: handlerFunc(handler.BookUpsert)},
+		{method: "GET", path: "/books/{id}", summary: "Get a book", handler: handlerFunc(handler.GetBook)},
+		{method: "PUT", path: "/books/{id}", summary: "Replace the name and author of a book", auth: true, body: db.Book{}, handler: handler.editBook(true)},
+		{method: "PATCH", path: "/books/{id}", summary: "Change the name or author of a book", auth: true, body: db.Book{}, handler: handler.editBook(false)},
+		{method: "DELETE", path: "/books/{id}", summary: "Delete a book", auth: true, handler: handlerFunc(handler.DeleteBook)},
+		{method: "POST", path: "/books/{id}/relist", summary: "Make a swapped book available again", auth: true, handler: handlerFunc(handler.RelistBook)},
+		{method: "POST", path: "/swaps", summary: "Request a swap of a book", auth: true, body: SwapRequest{}, status: http.StatusCreated, handler: handlerFunc(handler.RequestSwap)},
+		{method: "GET", path: "/swaps", summary: "List the swaps of the caller", auth: true, handler: handlerFunc(handler.ListSwaps)},
+		{method: "GET", path: "/swaps/{id}", summary: "Get a swap", auth: true, handler: handlerFunc(handler.GetSwap)},
+		{method: "POST", path: "/swaps/{id}/accept", summary: "Accept a swap request", auth: true, handler: handler.swapAction((*db.SwapService).Accept)},
+		{method: "POST", path: "/swaps/{id}/reject", summary: "Reject a swap request", auth: true, handler: handler.swapAction((*db.SwapService).Reject)},
+		{method: "POST", path: "/swaps/{id}/ship", summary: "Ship the book of a swap", auth: true, handler: handler.swapAction((*db.SwapService).Ship)},
+		{method: "POST", path: "/swaps/{id}/receive", summary: "Confirm that the book of a swap arrived", auth: true, handler: handler.swapAction((*db.SwapService).Receive)},
+		{method: "POST", path: "/swaps/{id}/cancel", summary: "Cancel a swap", auth: true, handler: handler.swapAction((*db.SwapService).Cancel)},
+		{method: "GET", path: "/swaps/{id}/tracking", summary: "Track the shipment of a swap", auth: true, handler: handler.swapAction((*db.SwapService).Track)},
+		{method: "GET", path: "/admin/outbox", summary: "List the posting events of the outbox", auth: true, admin: true, query: []string{"status"}, handler: handlerFunc(handler.ListOutbox)},
+		{method: "POST", path: "/admin/outbox/{id}/replay", summary: "Deliver a dead posting event again", auth: true, admin: true, handler: handlerFunc(handler.ReplayOutbox)},
+		{method: "PUT", path: "/admin/users/{id}/password", summary: "Set the password of a user, such as a seeded user", auth: true, admin: true, body: PasswordRequest{}, handler: handlerFunc(handler.SetUserPassword)},
+	}
+}
+
+// ConfigureServer configures the routes of this server and binds handler functions to them.
+// The routes are served under /v1 and, deprecated, without the version prefix.
 func ConfigureServer(handler *Handler) *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
+	router.Use(handler.trace, handler.authenticate)
+	router.NotFoundHandler = handler.trace(handlerFunc(func(r *http.Request) (int, *Response, error) {
+		return 0, nil, db.NewError(db.ErrNotFound, "route_not_found", "no such route "+r.URL.Path)
+	}))
 
-	router.Methods("GET").Path("/").Handler(http.HandlerFunc(handler.Index))
-	router.Methods("GET").Path("/books").Handler(http.HandlerFunc(handler.ListBooks))
-	router.Methods("POST").Path("/users").Handler(http.HandlerFunc(handler.UserUpsert))
-	router.Methods("GET").Path("/users/{id}").Handler(http.HandlerFunc(handler.ListUserByID))
-	router.Methods("POST").Path("/books/{id}").Handler(http.HandlerFunc(handler.SwapBook))
-	router.Methods("POST").Path("/books").Handler(http.HandlerFunc(handler.BookUpsert))
+	routes := handler.routes()
+	for i, rt := range routes {
+		switch {
+		case rt.admin:
+			routes[i].handler = handler.requireAdmin(rt.handler)
+		case rt.auth:
+			routes[i].handler = handler.requireCaller(rt.handler)
+		}
+	}
+	router.Methods("GET").Path("/openapi.json").Handler(openAPIHandler(routes))
+	router.Methods("GET").Path("/metrics").Handler(handler.metrics)
+	router.Methods("GET").Path("/healthz").Handler(handlerFunc(handler.Healthz))
+	router.Methods("GET").Path("/readyz").Handler(handlerFunc(handler.Readyz))
+	for _, rt := range routes {
+		router.Methods(rt.method).Path(apiVersion + rt.path).Handler(rt.handler)
+	}
+	for _, rt := range routes {
+		router.Methods(rt.method).Path(rt.path).Handler(deprecated(rt.handler))
+	}
 
 	return router
 }
+
+// deprecated marks the responses of an unversioned route as deprecated
+// and links them to the route of the current version.
+func deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+apiVersion+r.URL.Path+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,163 +1,287 @@
 package handlers
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
 
 	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
 	"github.com/gorilla/mux"
 )
 
+// maxBodySize is the largest request body that is read, 1 MiB.
+const maxBodySize = 1 << 20
+
 type Handler struct {
-	bs *db.BookService
-	us *db.UserService
+	bs     *db.BookService
+	us     *db.UserService
+	ss     *db.SwapService
+	auth   *db.AuthService
+	logger *slog.Logger
+	// metrics is served at /metrics, along with the requests and their latency.
+	metrics  *metrics.Registry
+	requests *metrics.Counter
+	latency  *metrics.Histogram
+	// draining is set once the server starts shutting down.
+	draining atomic.Bool
 }
 
-func NewHandler(bs *db.BookService, us *db.UserService) *Handler {
+func NewHandler(bs *db.BookService, us *db.UserService, ss *db.SwapService, auth *db.AuthService, logger *slog.Logger, reg *metrics.Registry) *Handler {
 	return &Handler{
-		bs: bs,
-		us: us,
+		bs:       bs,
+		us:       us,
+		ss:       ss,
+		auth:     auth,
+		logger:   logger,
+		metrics:  reg,
+		requests: reg.Counter("bookswap_http_requests_total", "HTTP requests served, by method, route and status.", "method", "route", "status"),
+		latency:  reg.Histogram("bookswap_http_request_duration_seconds", "Latency of HTTP requests, by method and route.", metrics.DefaultBuckets, "method", "route"),
 	}
 }
 
-func (handler *Handler) Index(w http.ResponseWriter, r *http.Request) {
+// UserRequest is the body of POST /users: a user and their password,
+// which is required for new users and optional for existing ones.
+type UserRequest struct {
+	db.User
+	Password string `json:"password,omitempty"`
+}
+
+func (handler *Handler) Index(r *http.Request) (int, *Response, error) {
 	// Send an HTTP status & a hardcoded message
 	resp := &Response{
 		Message: "Welcome to the BookSwap service!",
-		Books:   handler.bs.List(),
+		Books:   handler.bs.List(r.Context()),
 	}
-	writeResponse(w, http.StatusOK, resp)
+	return http.StatusOK, resp, nil
 }
 
-func (handler *Handler) ListBooks(w http.ResponseWriter, r *http.Request) {
+// ListBooks is invoked by HTTP GET /books. It supports the query parameters
+//   - q: words that the name or author must contain
+//   - author: the author, ignoring case
+//   - owner: the ID of the owner
+//   - country: the country of the owner, ignoring case
+//   - status: comma separated statuses, AVAILABLE by default, or ALL
+//   - sort: name, author or created, prefixed with - for descending order
+//   - limit: the page size
+//   - page_token: the next_page_token of the previous page
+func (handler *Handler) ListBooks(r *http.Request) (int, *Response, error) {
+	q, err := handler.bookQuery(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	page, err := handler.bs.Query(r.Context(), *q)
+	if err != nil {
+		return 0, nil, err
+	}
 	resp := &Response{
-		Books: handler.bs.List(),
+		Books:         page.Books,
+		NextPageToken: page.NextCursor,
+	}
+	return http.StatusOK, resp, nil
+}
+
+// bookQuery reads the query parameters of GET /books.
+func (handler *Handler) bookQuery(r *http.Request) (*db.BookQuery, error) {
+	params := r.URL.Query()
+	q := &db.BookQuery{
+		Search:   params.Get("q"),
+		Author:   params.Get("author"),
+		Sort:     params.Get("sort"),
+		Cursor:   params.Get("page_token"),
+		Statuses: []db.BookStatus{db.Available},
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, fmt.Errorf("%w: limit must be a number", db.ErrInvalidQuery)
+		}
+		q.Limit = n
+	}
+
+	switch status := params.Get("status"); status {
+	case "":
+	case "ALL":
+		q.Statuses = nil
+	default:
+		q.Statuses = nil
+		for _, name := range strings.Split(status, ",") {
+			s, err := db.ParseBookStatus(strings.TrimSpace(name))
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", db.ErrInvalidQuery, err)
+			}
+			q.Statuses = append(q.Statuses, s)
+		}
+	}
+
+	if owner := params.Get("owner"); owner != "" {
+		q.OwnerIDs = []string{owner}
+	}
+	if country := params.Get("country"); country != "" {
+		owners := make([]string, 0)
+		for _, u := range handler.us.ListByCountry(r.Context(), country) {
+			if q.OwnerIDs == nil || u.ID == q.OwnerIDs[0] {
+				owners = append(owners, u.ID)
+			}
+		}
+		q.OwnerIDs = owners
 	}
-	writeResponse(w, http.StatusOK, resp)
+	return q, nil
 }
 
-// UserUpsert is invoked by HTTP POST /users
-func (handler *Handler) UserUpsert(w http.ResponseWriter, r *http.Request) {
-	// Read the request body
+// UserUpsert is invoked by HTTP POST /users. It registers new users and
+// answers with a token for them; existing users can only update themselves.
+func (handler *Handler) UserUpsert(r *http.Request) (int, *Response, error) {
+	// Read the request body into a user
+	var req UserRequest
 	body, err := readRequestBody(r)
-	// Handle any errors & write an error HTTP status & response
 	if err != nil {
-		writeResponse(w, http.StatusInternalServerError, &Response{
-			Error: fmt.Errorf("invalid user body: %v", err).Error(),
-		})
-		return
-	}
-	// Initialize a user to unmarshal request body into
-	var user db.User
-	if err := json.Unmarshal(body, &user); err != nil {
-		writeResponse(w, http.StatusUnprocessableEntity, &Response{
-			Error: fmt.Errorf("invalid user body:%v", err).Error(),
-		})
-		return
+		return 0, nil, err
 	}
+	if err := decodeBody(body, &req); err != nil {
+		return 0, nil, err
+	}
+	if err := req.User.Validate(); err != nil {
+		return 0, nil, err
+	}
+
+	registering := req.ID == "" || handler.us.Exists(r.Context(), req.ID) != nil
+	if !registering {
+		userID, err := caller(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		if userID != req.ID {
+			return 0, nil, fmt.Errorf("%w: users can only change themselves", db.ErrNotAllowed)
+		}
+	}
+	if registering || req.Password != "" {
+		if err := db.ValidatePassword(req.Password); err != nil {
+			return 0, nil, err
+		}
+	}
+
 	// Call the repository method corresponding to the operation
-	u, err := handler.us.Upsert(user)
+	u, err := handler.us.Upsert(r.Context(), req.User)
 	if err != nil {
-		writeResponse(w, http.StatusBadRequest, &Response{
-			Error: err.Error(),
-		})
-		return
+		return 0, nil, err
 	}
-
-	writeResponse(w, http.StatusOK, &Response{
+	resp := &Response{
 		User: &u,
-	})
+	}
+	if registering || req.Password != "" {
+		if err := handler.auth.SetPassword(u.ID, req.Password); err != nil {
+			return 0, nil, err
+		}
+	}
+	if registering {
+		if resp.Token, err = handler.auth.Token(u.ID); err != nil {
+			return 0, nil, err
+		}
+	}
+	return http.StatusOK, resp, nil
 }
 
 // ListUserByID is invoked by HTTP GET /users/{id}.
-func (handler *Handler) ListUserByID(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) ListUserByID(r *http.Request) (int, *Response, error) {
 	userID := mux.Vars(r)["id"]
-	user, book, error := handler.us.Get(userID)
-	if error != nil {
-		writeResponse(w, http.StatusNotFound, &Response{
-			Error: error.Error(),
-		})
+	user, book, err := handler.us.Get(r.Context(), userID)
+	if err != nil {
+		return 0, nil, err
 	}
-	writeResponse(w, http.StatusOK, &Response{
+	return http.StatusOK, &Response{
 		Books: book,
 		User:  user,
-	})
+	}, nil
 }
 
-// SwapBook is invoked by POST /books/{id}
-func (h *Handler) SwapBook(w http.ResponseWriter, r *http.Request) {
+// SwapBook is invoked by POST /books/{id} and requests the book for the
+// caller, like POST /swaps. The owner still has to accept the swap.
+func (h *Handler) SwapBook(r *http.Request) (int, *Response, error) {
 	bookID := mux.Vars(r)["id"]
-	userID := r.URL.Query().Get("user")
-	if err := h.us.Exists(userID); err != nil {
-		writeResponse(w, http.StatusBadRequest, &Response{
-			Error: err.Error(),
-		})
-		return
-	}
-	_, err := h.bs.SwapBook(bookID, userID)
+	userID, err := caller(r)
 	if err != nil {
-		writeResponse(w, http.StatusNotFound, &Response{
-			Error: err.Error(),
-		})
-		return
+		return 0, nil, err
 	}
 
-	user, books, err := h.us.Get(userID)
+	swap, err := h.ss.Request(r.Context(), bookID, userID)
 	if err != nil {
-		writeResponse(w, http.StatusInternalServerError, &Response{
-			Error: err.Error(),
-		})
-		return
+		return 0, nil, err
 	}
-
-	writeResponse(w, http.StatusOK, &Response{
-		User:  user,
-		Books: books,
-	})
+	return http.StatusCreated, &Response{
+		Swap: swap,
+	}, nil
 }
 
-// BookUpsert is invoked by HTTP POST /books.
-func (h *Handler) BookUpsert(w http.ResponseWriter, r *http.Request) {
-	body, err := readRequestBody(r)
+// RelistBook is invoked by POST /books/{id}/relist by the owner of the book.
+func (h *Handler) RelistBook(r *http.Request) (int, *Response, error) {
+	bookID := mux.Vars(r)["id"]
+	userID, err := caller(r)
 	if err != nil {
-		writeResponse(w, http.StatusInternalServerError, &Response{
-			Error: fmt.Errorf("invalid book body:%v", err).Error(),
-		})
-		return
+		return 0, nil, err
 	}
 
+	book, err := h.bs.Relist(r.Context(), bookID, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, &Response{
+		Books: []db.Book{*book},
+	}, nil
+}
+
+// BookUpsert is invoked by HTTP POST /books. Users can only create and
+// update books that they own, and own the books they create by default.
+func (h *Handler) BookUpsert(r *http.Request) (int, *Response, error) {
+	userID, err := caller(r)
+	if err != nil {
+		return 0, nil, err
+	}
 	var book db.Book
-	if error := json.Unmarshal(body, &book); error != nil {
-		writeResponse(w, http.StatusUnprocessableEntity, &Response{
-			Error: fmt.Errorf("invalid book body:%v", err).Error(),
-		})
+	body, err := readRequestBody(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := decodeBody(body, &book); err != nil {
+		return 0, nil, err
+	}
+	if err := book.Validate(); err != nil {
+		return 0, nil, err
 	}
 
-	if err := h.us.Exists(book.OwnerID); err != nil {
-		writeResponse(w, http.StatusBadRequest, &Response{
-			Error: err.Error(),
-		})
+	if book.OwnerID == "" {
+		book.OwnerID = userID
+	}
+	if book.OwnerID != userID {
+		return 0, nil, fmt.Errorf("%w: users can only own their books", db.ErrNotAllowed)
 	}
 
 	// Call the repository method corresponding to the operation
-	book = h.bs.Upsert(book)
+	book, err = h.bs.Upsert(r.Context(), book)
+	if err != nil {
+		return 0, nil, err
+	}
 	// Send an HTTP success status & the return value from the repo
-	writeResponse(w, http.StatusOK, &Response{
+	return http.StatusOK, &Response{
 		Books: []db.Book{book},
-	})
+	}, nil
 }
 
 // readRequestBody is a helper method that
 // allows to read a request body and return any errors.
 func readRequestBody(r *http.Request) ([]byte, error) {
-	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
 	if err != nil {
-		return []byte{}, err
+		return nil, fmt.Errorf("%w: %w", errUnreadableBody, err)
 	}
 	if err := r.Body.Close(); err != nil {
-		return []byte{}, err
+		return nil, fmt.Errorf("%w: %w", errUnreadableBody, err)
 	}
-	return body, err
+	return body, nil
 }
@@ -0,0 +1,306 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/handlers"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// workers is the number of goroutines sending requests at the same time.
+const workers = 50
+
+// testAuth issues the tokens of the users in the tests. It shares the secret
+// of the servers of newServer and stretches passwords cheaply.
+var testAuth = db.NewAuthService(db.NewMemoryCredentialRepository(nil), db.AuthConfig{
+	Secret:     []byte("test-secret"),
+	Iterations: 1000,
+})
+
+// testAdmin is the administrator of the servers of newServer.
+var testAdmin = db.User{ID: uuid.NewString(), Name: "Admin"}
+
+func newServer(t *testing.T, books []db.Book, users []db.User) *httptest.Server {
+	t.Helper()
+	return newServerWithLogger(t, books, users, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// newServerWithLogger creates a server whose handlers and services log to logger.
+// Besides users, the server knows testAdmin, who administers it.
+func newServerWithLogger(t *testing.T, books []db.Book, users []db.User, logger *slog.Logger) *httptest.Server {
+	t.Helper()
+	reg := metrics.NewRegistry()
+	bs := db.NewBookServiceWithRepository(db.NewMemoryBookRepository(books), db.NewMemoryOutboxRepository(nil), db.NewPostingService(), logger, reg)
+	users = append(users[:len(users):len(users)], testAdmin)
+	us := db.NewUserServiceWithRepository(db.NewMemoryUserRepository(users), bs, logger, reg)
+	ss := db.NewSwapService(db.NewMemorySwapRepository(nil), bs)
+	auth := db.NewAuthService(db.NewMemoryCredentialRepository(nil), db.AuthConfig{
+		Secret:     []byte("test-secret"),
+		Iterations: 1000,
+		Admins:     []string{testAdmin.ID},
+	})
+	srv := httptest.NewServer(handlers.ConfigureServer(handlers.NewHandler(bs, us, ss, auth, logger, reg)))
+	t.Cleanup(srv.Close)
+	ctx, cancel := context.WithCancel(context.Background())
+	go bs.Outbox().Run(ctx, 10*time.Millisecond)
+	t.Cleanup(cancel)
+	return srv
+}
+
+// post, get and send are called from many goroutines, so they report
+// failures with assert rather than require.
+func post(t *testing.T, url string, body any) (int, handlers.Response) {
+	t.Helper()
+	return send(t, http.MethodPost, url, body)
+}
+
+func get(t *testing.T, url string) (int, handlers.Response) {
+	t.Helper()
+	return send(t, http.MethodGet, url, nil)
+}
+
+// send makes a request with any method, such as PUT, PATCH or DELETE.
+func send(t *testing.T, method, url string, body any) (int, handlers.Response) {
+	t.Helper()
+	var r handlers.Response
+	status := request(t, method, url, body, &r)
+	return status, r
+}
+
+// sendProblem makes a request that is expected to fail and returns its problem details.
+func sendProblem(t *testing.T, method, url string, body any) (int, handlers.Problem) {
+	t.Helper()
+	var p handlers.Problem
+	status := request(t, method, url, body, &p)
+	return status, p
+}
+
+// request sends body as JSON and decodes the response into v.
+// Requests to URLs with a ?user= parameter are authenticated as that user.
+func request(t *testing.T, method, url string, body, v any) int {
+	t.Helper()
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if !assert.Nil(t, err) {
+			return 0
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, url, r)
+	if !assert.Nil(t, err) {
+		return 0
+	}
+	if userID := req.URL.Query().Get("user"); userID != "" {
+		token, err := testAuth.Token(userID)
+		if !assert.Nil(t, err) {
+			return 0
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		return 0
+	}
+	defer resp.Body.Close()
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(v))
+	return resp.StatusCode
+}
+
+func TestConcurrentSwapBook(t *testing.T) {
+	// Arrange
+	book := db.Book{ID: uuid.NewString(), Name: "Contested", OwnerID: uuid.NewString(), Status: db.Available}
+	var users []db.User
+	for i := 0; i < workers; i++ {
+		users = append(users, db.User{ID: uuid.NewString(), Name: fmt.Sprintf("User %d", i)})
+	}
+	srv := newServer(t, []db.Book{book}, users)
+
+	// Act
+	var wg sync.WaitGroup
+	var winners atomic.Int32
+	for _, u := range users {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			status, _ := post(t, fmt.Sprintf("%s/books/%s?user=%s", srv.URL, book.ID, userID), nil)
+			if status == http.StatusCreated {
+				winners.Add(1)
+			}
+		}(u.ID)
+	}
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, int32(1), winners.Load())
+	status, resp := get(t, srv.URL+"/books")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, resp.Books)
+}
+
+func TestConcurrentUpserts(t *testing.T) {
+	// Arrange
+	srv := newServer(t, nil, nil)
+
+	// Act
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			status, resp := post(t, srv.URL+"/users", handlers.UserRequest{
+				User:     db.User{Name: fmt.Sprintf("User %d", i), Address: "Partizanska 1", PostCode: "1000", Country: "MK"},
+				Password: "correct horse",
+			})
+			if !assert.Equal(t, http.StatusOK, status) {
+				return
+			}
+			status, _ = post(t, srv.URL+"/books?user="+resp.User.ID, db.Book{Name: fmt.Sprintf("Book %d", i), Author: "Author", OwnerID: resp.User.ID})
+			assert.Equal(t, http.StatusOK, status)
+			status, _ = get(t, srv.URL+"/users/"+resp.User.ID)
+			assert.Equal(t, http.StatusOK, status)
+			status, _ = get(t, srv.URL+"/books")
+			assert.Equal(t, http.StatusOK, status)
+		}(i)
+	}
+	wg.Wait()
+
+	// Assert
+	status, resp := get(t, srv.URL+"/books?limit=100")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, resp.Books, workers)
+}
+
+func TestConcurrentSwapsOfDifferentBooks(t *testing.T) {
+	// Arrange
+	user := db.User{ID: uuid.NewString(), Name: "Collector"}
+	var books []db.Book
+	for i := 0; i < workers; i++ {
+		books = append(books, db.Book{ID: uuid.NewString(), Name: fmt.Sprintf("Book %d", i), OwnerID: uuid.NewString(), Status: db.Available})
+	}
+	srv := newServer(t, books, []db.User{user})
+
+	// Act
+	var wg sync.WaitGroup
+	for _, b := range books {
+		wg.Add(1)
+		go func(bookID string) {
+			defer wg.Done()
+			status, _ := post(t, fmt.Sprintf("%s/books/%s?user=%s", srv.URL, bookID, user.ID), nil)
+			assert.Equal(t, http.StatusCreated, status)
+		}(b.ID)
+	}
+	wg.Wait()
+
+	// Assert
+	status, resp := get(t, srv.URL+"/swaps?user="+user.ID)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, resp.Swaps, workers)
+}
+
+func TestListBooksQuery(t *testing.T) {
+	// Arrange
+	alice := db.User{ID: uuid.NewString(), Name: "Alice", Country: "Macedonia"}
+	bob := db.User{ID: uuid.NewString(), Name: "Bob", Country: "Germany"}
+	books := []db.Book{
+		{ID: uuid.NewString(), Name: "Dune", Author: "Frank Herbert", OwnerID: alice.ID, Status: db.Available},
+		{ID: uuid.NewString(), Name: "Children of Dune", Author: "Frank Herbert", OwnerID: bob.ID, Status: db.Available},
+		{ID: uuid.NewString(), Name: "Dune Messiah", Author: "Frank Herbert", OwnerID: alice.ID, Status: db.Swapped},
+	}
+	srv := newServer(t, books, []db.User{alice, bob})
+
+	tests := map[string]struct {
+		query      string
+		wantStatus int
+		wantNames  []string
+	}{
+		"available by default": {
+			query:      "?q=dune",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"Children of Dune", "Dune"},
+		},
+		"every status": {
+			query:      "?q=dune&status=ALL&sort=-name",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"Dune Messiah", "Dune", "Children of Dune"},
+		},
+		"country of the owner": {
+			query:      "?country=macedonia&status=AVAILABLE,SWAPPED",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"Dune", "Dune Messiah"},
+		},
+		"owner outside the country": {
+			query:      "?owner=" + bob.ID + "&country=Macedonia",
+			wantStatus: http.StatusOK,
+		},
+		"unknown status": {
+			query:      "?status=LOST",
+			wantStatus: http.StatusBadRequest,
+		},
+		"unknown sort": {
+			query:      "?sort=owner",
+			wantStatus: http.StatusBadRequest,
+		},
+		"invalid limit": {
+			query:      "?limit=ten",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			status, resp := get(t, srv.URL+"/books"+tc.query)
+
+			// Assert
+			require.Equal(t, tc.wantStatus, status)
+			var names []string
+			for _, b := range resp.Books {
+				names = append(names, b.Name)
+			}
+			assert.Equal(t, tc.wantNames, names)
+		})
+	}
+}
+
+func TestListBooksPages(t *testing.T) {
+	// Arrange
+	var books []db.Book
+	for i := 0; i < 5; i++ {
+		books = append(books, db.Book{ID: uuid.NewString(), Name: fmt.Sprintf("Book %d", i), Status: db.Available})
+	}
+	srv := newServer(t, books, nil)
+
+	// Act
+	var names []string
+	url := srv.URL + "/books?limit=2"
+	for pages := 0; pages < 5; pages++ {
+		status, resp := get(t, url)
+		require.Equal(t, http.StatusOK, status)
+		for _, b := range resp.Books {
+			names = append(names, b.Name)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		url = srv.URL + "/books?limit=2&page_token=" + resp.NextPageToken
+	}
+
+	// Assert
+	assert.Equal(t, []string{"Book 0", "Book 1", "Book 2", "Book 3", "Book 4"}, names)
+}
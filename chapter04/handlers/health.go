@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+)
+
+// errDraining fails the readiness of a server that is shutting down,
+// so that load balancers stop sending it requests.
+var errDraining = errors.New("shutting down")
+
+// Drain marks the server as shutting down: /readyz fails from then on,
+// while the requests in flight are still served.
+func (h *Handler) Drain() {
+	h.draining.Store(true)
+}
+
+// Healthz is invoked by HTTP GET /healthz. It succeeds as long as the server
+// answers requests, so that an orchestrator only restarts a hung server.
+func (h *Handler) Healthz(r *http.Request) (int, *Response, error) {
+	return http.StatusOK, &Response{Message: "ok"}, nil
+}
+
+// Readyz is invoked by HTTP GET /readyz. It checks the storage of every
+// service and the dispatcher of posting orders, and answers
+// 503 Service Unavailable if any of them fails or the server is draining.
+func (h *Handler) Readyz(r *http.Request) (int, *Response, error) {
+	checks := map[string]func() error{
+		"books":       h.bs.Check,
+		"users":       h.us.Check,
+		"swaps":       h.ss.Check,
+		"credentials": h.auth.Check,
+		"dispatcher":  h.bs.Outbox().Check,
+		"server": func() error {
+			if h.draining.Load() {
+				return errDraining
+			}
+			return nil
+		},
+	}
+
+	status, resp := http.StatusOK, &Response{Message: "ready", Checks: make(map[string]string, len(checks))}
+	for name, check := range checks {
+		if err := check(); err != nil {
+			status, resp.Message = http.StatusServiceUnavailable, "not ready"
+			resp.Checks[name] = err.Error()
+			continue
+		}
+		resp.Checks[name] = "ok"
+	}
+	return status, resp, nil
+}
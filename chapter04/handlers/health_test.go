@@ -0,0 +1,74 @@
+package handlers_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/handlers"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthz(t *testing.T) {
+	// Arrange
+	srv := newServer(t, nil, nil)
+
+	// Act
+	status, resp := get(t, srv.URL+"/healthz")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok", resp.Message)
+}
+
+func TestReadyz(t *testing.T) {
+	// Arrange
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reg := metrics.NewRegistry()
+	bs := db.NewBookServiceWithRepository(db.NewMemoryBookRepository(nil), db.NewMemoryOutboxRepository(nil), db.NewPostingService(), logger, reg)
+	us := db.NewUserServiceWithRepository(db.NewMemoryUserRepository(nil), bs, logger, reg)
+	ss := db.NewSwapService(db.NewMemorySwapRepository(nil), bs)
+	h := handlers.NewHandler(bs, us, ss, testAuth, logger, reg)
+	srv := httptest.NewServer(handlers.ConfigureServer(h))
+	t.Cleanup(srv.Close)
+
+	// The dispatcher is not running yet.
+	status, resp := get(t, srv.URL+"/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "not ready", resp.Message)
+	assert.Contains(t, resp.Checks["dispatcher"], "posting dispatcher is unavailable")
+	assert.Equal(t, "ok", resp.Checks["books"])
+
+	// Act
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go bs.Outbox().Run(ctx, time.Hour)
+
+	// Assert
+	require.Eventually(t, func() bool {
+		status, _ := get(t, srv.URL+"/readyz")
+		return status == http.StatusOK
+	}, time.Second, time.Millisecond)
+	_, resp = get(t, srv.URL+"/readyz")
+	assert.Equal(t, "ready", resp.Message)
+	for _, check := range []string{"books", "users", "swaps", "credentials", "dispatcher", "server"} {
+		assert.Equal(t, "ok", resp.Checks[check], check)
+	}
+
+	// Act
+	h.Drain()
+
+	// Assert
+	status, resp = get(t, srv.URL+"/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "shutting down", resp.Checks["server"])
+	status, _ = get(t, srv.URL+"/healthz")
+	assert.Equal(t, http.StatusOK, status)
+}
@@ -0,0 +1,55 @@
+package handlers_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	// Arrange
+	user := db.User{ID: uuid.NewString(), Name: "Reader"}
+	books := []db.Book{
+		{ID: uuid.NewString(), Name: "Wanted", Author: "Author", OwnerID: uuid.NewString(), Status: db.Available},
+		{ID: uuid.NewString(), Name: "Kept", Author: "Author", OwnerID: uuid.NewString(), Status: db.Available},
+	}
+	srv := newServer(t, books, []db.User{user})
+	for _, b := range books {
+		status, _ := get(t, srv.URL+"/v1/books/"+b.ID)
+		require.Equal(t, http.StatusOK, status)
+	}
+	status, _ := get(t, srv.URL+"/v1/books/not-found")
+	require.Equal(t, http.StatusNotFound, status)
+	status, _ = post(t, fmt.Sprintf("%s/v1/books/%s?user=%s", srv.URL, books[0].ID, user.ID), nil)
+	require.Equal(t, http.StatusCreated, status)
+
+	// Act
+	resp, err := http.Get(srv.URL + "/metrics")
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.Nil(t, err)
+
+	// Assert
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, metrics.ContentType, resp.Header.Get("Content-Type"))
+	for _, line := range []string{
+		`bookswap_http_requests_total{method="GET",route="/v1/books/{id}",status="200"} 2`,
+		`bookswap_http_requests_total{method="GET",route="/v1/books/{id}",status="404"} 1`,
+		`bookswap_http_requests_total{method="POST",route="/v1/books/{id}",status="201"} 1`,
+		`bookswap_http_request_duration_seconds_count{method="GET",route="/v1/books/{id}"} 3`,
+		`bookswap_http_request_duration_seconds_bucket{method="GET",route="/v1/books/{id}",le="+Inf"} 3`,
+		`bookswap_swaps_total{action="request"} 1`,
+		`bookswap_books{status="AVAILABLE"} 1`,
+		`bookswap_books{status="REQUESTED"} 1`,
+	} {
+		assert.Contains(t, string(body), line+"\n")
+	}
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// openAPIVersion is the version of the OpenAPI specification of the document.
+const openAPIVersion = "3.0.3"
+
+// pathParam matches the {name} variables of route paths, which OpenAPI shares with mux.
+var pathParam = regexp.MustCompile(`{([^}]+)}`)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schema is a JSON schema of the OpenAPI document.
+type schema map[string]any
+
+// openAPIDocument is the OpenAPI document of the API.
+type openAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       map[string]string                `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+}
+
+type components struct {
+	Schemas         map[string]schema `json:"schemas"`
+	SecuritySchemes map[string]schema `json:"securitySchemes"`
+}
+
+type operation struct {
+	Summary     string                `json:"summary"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *content              `json:"requestBody,omitempty"`
+	Responses   map[string]*content   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required,omitempty"`
+	Schema   schema `json:"schema"`
+}
+
+// content is a request body or a response of an operation.
+type content struct {
+	Description string            `json:"description,omitempty"`
+	Content     map[string]schema `json:"content"`
+}
+
+// openAPIHandler serves the OpenAPI document of the routes, which is
+// generated once from the routes and the types of their bodies.
+func openAPIHandler(routes []route) http.Handler {
+	doc, err := json.Marshal(openAPI(routes))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.Write(doc)
+	})
+}
+
+// openAPI documents the routes under the version prefix of the API.
+func openAPI(routes []route) *openAPIDocument {
+	doc := &openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    map[string]string{"title": "BookSwap", "version": strings.TrimPrefix(apiVersion, "/")},
+		Paths:   make(map[string]map[string]*operation),
+		Components: components{
+			Schemas: make(map[string]schema),
+			SecuritySchemes: map[string]schema{
+				"bearer": {"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+	response := doc.schemaOf(reflect.TypeOf(Response{}))
+	problem := doc.schemaOf(reflect.TypeOf(Problem{}))
+
+	for _, rt := range routes {
+		op := &operation{
+			Summary: rt.summary,
+			Responses: map[string]*content{
+				"default": {
+					Description: "Problem details of the error",
+					Content:     map[string]schema{"application/problem+json": problem},
+				},
+			},
+		}
+		status := rt.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		op.Responses[strconv.Itoa(status)] = &content{
+			Description: http.StatusText(status),
+			Content:     map[string]schema{"application/json": response},
+		}
+		for _, m := range pathParam.FindAllStringSubmatch(rt.path, -1) {
+			op.Parameters = append(op.Parameters, parameter{Name: m[1], In: "path", Required: true, Schema: schema{"type": "string"}})
+		}
+		for _, name := range rt.query {
+			op.Parameters = append(op.Parameters, parameter{Name: name, In: "query", Schema: schema{"type": "string"}})
+		}
+		if rt.body != nil {
+			op.RequestBody = &content{
+				Content: map[string]schema{"application/json": doc.schemaOf(reflect.TypeOf(rt.body))},
+			}
+		}
+		if rt.auth || rt.admin {
+			op.Security = []map[string][]string{{"bearer": {}}}
+		}
+
+		path := apiVersion + rt.path
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*operation)
+		}
+		doc.Paths[path][strings.ToLower(rt.method)] = op
+	}
+	return doc
+}
+
+// schemaOf returns the schema of the JSON encoding of values of t.
+// Structs are added to the components of the document and referenced.
+func (doc *openAPIDocument) schemaOf(t reflect.Type) schema {
+	switch {
+	case t == timeType:
+		return schema{"type": "string", "format": "date-time"}
+	case t.Implements(textMarshalerType):
+		return schema{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return doc.schemaOf(t.Elem())
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return schema{"type": "array", "items": doc.schemaOf(t.Elem())}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": doc.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if _, ok := doc.Components.Schemas[t.Name()]; !ok {
+			// Reserve the name first, so that recursive types terminate.
+			doc.Components.Schemas[t.Name()] = schema{}
+			doc.Components.Schemas[t.Name()] = doc.structSchema(t)
+		}
+		return schema{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return schema{}
+	}
+}
+
+// structSchema returns the schema of the JSON object of a struct.
+// Embedded structs contribute their fields, as they do in encoding/json.
+func (doc *openAPIDocument) structSchema(t reflect.Type) schema {
+	properties := make(map[string]schema)
+	var required []string
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				addFields(f.Type)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = doc.schemaOf(f.Type)
+			if strings.Contains(","+f.Tag.Get("validate")+",", ",required,") {
+				required = append(required, name)
+			}
+		}
+	}
+	addFields(t)
+
+	s := schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
@@ -0,0 +1,170 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/handlers"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAPIDocument is the part of the OpenAPI document that the tests read.
+type openAPIDocument struct {
+	OpenAPI string `json:"openapi"`
+	Paths   map[string]map[string]struct {
+		Summary   string                     `json:"summary"`
+		Responses map[string]json.RawMessage `json:"responses"`
+		Security  []map[string][]string      `json:"security"`
+	} `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+			Required   []string                   `json:"required"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func getOpenAPI(t *testing.T, url string) openAPIDocument {
+	t.Helper()
+	resp, err := http.Get(url + "/openapi.json")
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var doc openAPIDocument
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&doc))
+	return doc
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	// Arrange
+	bs := db.NewBookService(nil, db.NewPostingService())
+	us := db.NewUserService(nil, bs)
+	ss := db.NewSwapService(db.NewMemorySwapRepository(nil), bs)
+	router := handlers.ConfigureServer(handlers.NewHandler(bs, us, ss, testAuth, slog.Default(), metrics.NewRegistry()))
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	// Act
+	doc := getOpenAPI(t, srv.URL)
+
+	// Assert
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		require.Nil(t, err)
+		methods, err := route.GetMethods()
+		require.Nil(t, err)
+		// The document, the metrics and the probes describe the server rather than being part of the API.
+		switch path {
+		case "/openapi.json", "/metrics", "/healthz", "/readyz":
+			return nil
+		}
+		// Unversioned routes are aliases of the documented /v1 routes.
+		if !strings.HasPrefix(path, "/v1/") {
+			path = "/v1" + path
+		}
+		for _, method := range methods {
+			op, ok := doc.Paths[path][strings.ToLower(method)]
+			if assert.True(t, ok, "%s %s is not documented", method, path) {
+				assert.NotEmpty(t, op.Summary, "%s %s has no summary", method, path)
+				assert.NotEmpty(t, op.Responses, "%s %s has no responses", method, path)
+			}
+		}
+		return nil
+	})
+	require.Nil(t, err)
+}
+
+func TestOpenAPISecurityIsEnforced(t *testing.T) {
+	// Arrange
+	srv := newServer(t, nil, nil)
+	doc := getOpenAPI(t, srv.URL)
+
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			if len(op.Security) == 0 {
+				continue
+			}
+			t.Run(method+" "+path, func(t *testing.T) {
+				// Act
+				url := srv.URL + strings.ReplaceAll(path, "{id}", uuid.NewString())
+				status, problem := sendProblem(t, strings.ToUpper(method), url, nil)
+
+				// Assert
+				assert.Equal(t, http.StatusUnauthorized, status)
+				assert.Equal(t, "authentication_required", problem.Code)
+			})
+		}
+	}
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	// Arrange
+	srv := newServer(t, nil, nil)
+
+	// Act
+	doc := getOpenAPI(t, srv.URL)
+
+	// Assert
+	book := doc.Components.Schemas["Book"]
+	assert.Contains(t, book.Properties, "owner_id")
+	assert.Contains(t, book.Properties, "created_at")
+	assert.ElementsMatch(t, []string{"name", "author"}, book.Required)
+	// UserRequest embeds the user, so its fields are those of the user and the password.
+	request := doc.Components.Schemas["UserRequest"]
+	assert.Contains(t, request.Properties, "post_code")
+	assert.Contains(t, request.Properties, "password")
+	assert.Contains(t, doc.Components.Schemas["Response"].Properties, "next_page_token")
+	assert.Contains(t, doc.Components.Schemas["Problem"].Properties, "code")
+	assert.Contains(t, doc.Paths["/v1/swaps"]["post"].Responses, "201")
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	// Arrange
+	book := db.Book{ID: uuid.NewString(), Name: "Versioned", Author: "Author", Status: db.Available}
+	srv := newServer(t, []db.Book{book}, nil)
+
+	tests := map[string]struct {
+		path           string
+		wantDeprecated bool
+	}{
+		"current version": {
+			path: "/v1/books/" + book.ID,
+		},
+		"legacy alias": {
+			path:           "/books/" + book.ID,
+			wantDeprecated: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			resp, err := http.Get(srv.URL + tc.path)
+			require.Nil(t, err)
+			defer resp.Body.Close()
+
+			// Assert
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			var r handlers.Response
+			require.Nil(t, json.NewDecoder(resp.Body).Decode(&r))
+			assert.Equal(t, book.ID, r.Books[0].ID)
+			if tc.wantDeprecated {
+				assert.Equal(t, "true", resp.Header.Get("Deprecation"))
+				assert.Equal(t, fmt.Sprintf(`</v1/books/%s>; rel="successor-version"`, book.ID), resp.Header.Get("Link"))
+			} else {
+				assert.Empty(t, resp.Header.Get("Deprecation"))
+			}
+		})
+	}
+}
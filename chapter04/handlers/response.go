@@ -2,17 +2,59 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
 )
 
+var (
+	errUnreadableBody = db.NewError(db.ErrInvalid, "unreadable_body", "request body could not be read")
+	errInvalidBody    = db.NewError(db.ErrInvalid, "invalid_body", "request body is not valid JSON")
+)
+
 type Response struct {
-	Message string    `json:"message,omitempty"`
-	Error   string    `json:"error,omitempty"`
-	Books   []db.Book `json:"books,omitempty"`
-	User    *db.User  `json:"user,omitempty"`
+	Message string           `json:"message,omitempty"`
+	Books   []db.Book        `json:"books,omitempty"`
+	User    *db.User         `json:"user,omitempty"`
+	Swap    *db.Swap         `json:"swap,omitempty"`
+	Swaps   []db.Swap        `json:"swaps,omitempty"`
+	Event   *db.OutboxEvent  `json:"event,omitempty"`
+	Events  []db.OutboxEvent `json:"events,omitempty"`
+	// NextPageToken is passed as page_token to get the next page of a list.
+	NextPageToken string `json:"next_page_token,omitempty"`
+	// Token is the bearer token of a user that logged in or registered.
+	Token string `json:"token,omitempty"`
+	// Checks is the outcome of every readiness check, ok or the failure.
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Problem is the RFC 7807 problem details body of every error response.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is a machine readable code of the error, such as book_not_found.
+	Code string `json:"code"`
+	// Errors lists the invalid fields of a rejected user or book.
+	Errors []db.FieldError `json:"errors,omitempty"`
+}
+
+// handlerFunc is an HTTP handler that returns its status and response,
+// or the error that prevented them, so that every request is answered
+// with exactly one response.
+type handlerFunc func(r *http.Request) (int, *Response, error)
+
+func (f handlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status, resp, err := f(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	writeResponse(w, status, resp)
 }
 
 func writeResponse(w http.ResponseWriter, status int, resp *Response) {
@@ -25,3 +67,70 @@ func writeResponse(w http.ResponseWriter, status int, resp *Response) {
 		fmt.Fprintf(w, "error encoding resp %v:%s", resp, err)
 	}
 }
+
+// writeProblem writes the problem describing err. The details of
+// internal errors are only logged, by trace, rather than sent to the client.
+func writeProblem(w http.ResponseWriter, r *http.Request, err error) {
+	if rec, ok := w.(*statusRecorder); ok {
+		rec.err = err
+	}
+	status := errorStatus(err)
+	p := &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   err.Error(),
+		Instance: r.URL.Path,
+		Code:     db.ErrorCode(err),
+	}
+	var verr *db.ValidationError
+	if errors.As(err, &verr) {
+		p.Errors = verr.Errors
+	}
+	if status == http.StatusInternalServerError {
+		p.Detail = ""
+	}
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		fmt.Fprintf(w, "error encoding problem %v:%s", p, err)
+	}
+}
+
+// errorStatus returns the HTTP status for the kind of an error of the services.
+func errorStatus(err error) int {
+	var maxErr *http.MaxBytesError
+	var verr *db.ValidationError
+	switch {
+	case errors.As(err, &maxErr):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, &verr):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, db.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, db.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, db.ErrUnavailable):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// decodeBody decodes the JSON request body into v.
+func decodeBody(body []byte, v any) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidBody, err)
+	}
+	return nil
+}
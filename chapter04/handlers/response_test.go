@@ -0,0 +1,107 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/handlers"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblems(t *testing.T) {
+	// Arrange
+	user := db.User{ID: uuid.NewString(), Name: "Reader"}
+	srv := newServer(t, nil, []db.User{user})
+	token, err := testAuth.Token(user.ID)
+	require.Nil(t, err)
+
+	tests := map[string]struct {
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		"unknown book": {
+			method:     http.MethodGet,
+			path:       "/books/not-found",
+			wantStatus: http.StatusNotFound,
+			wantCode:   "book_not_found",
+		},
+		"unknown route": {
+			method:     http.MethodGet,
+			path:       "/not-found",
+			wantStatus: http.StatusNotFound,
+			wantCode:   "route_not_found",
+		},
+		"malformed body": {
+			method:     http.MethodPost,
+			path:       "/books",
+			body:       "{",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_body",
+		},
+		"body too large": {
+			method:     http.MethodPost,
+			path:       "/books",
+			body:       `{"name":"` + strings.Repeat("a", 2<<20) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   "unreadable_body",
+		},
+		"invalid query": {
+			method:     http.MethodGet,
+			path:       "/books?sort=owner",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_query",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			req, err := http.NewRequest(tc.method, srv.URL+tc.path, bytes.NewBufferString(tc.body))
+			require.Nil(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			// Act
+			resp, err := http.DefaultClient.Do(req)
+			require.Nil(t, err)
+			defer resp.Body.Close()
+
+			// Assert
+			require.Equal(t, tc.wantStatus, resp.StatusCode)
+			assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+			var p handlers.Problem
+			require.Nil(t, json.NewDecoder(resp.Body).Decode(&p))
+			assert.Equal(t, "about:blank", p.Type)
+			assert.Equal(t, http.StatusText(tc.wantStatus), p.Title)
+			assert.Equal(t, tc.wantStatus, p.Status)
+			assert.Equal(t, tc.wantCode, p.Code)
+			assert.NotEmpty(t, p.Detail)
+			assert.Equal(t, strings.SplitN(tc.path, "?", 2)[0], p.Instance)
+		})
+	}
+}
+
+func TestUnauthorizedProblem(t *testing.T) {
+	// Arrange
+	srv := newServer(t, nil, nil)
+
+	// Act
+	resp, err := http.Post(srv.URL+"/swaps", "application/json", nil)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	// Assert
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "Bearer", resp.Header.Get("WWW-Authenticate"))
+	var p handlers.Problem
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&p))
+	assert.Equal(t, "authentication_required", p.Code)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/gorilla/mux"
+)
+
+// SwapRequest is the body of POST /swaps.
+type SwapRequest struct {
+	BookID string `json:"book_id"`
+}
+
+// RequestSwap is invoked by HTTP POST /swaps and requests the book for the caller.
+func (h *Handler) RequestSwap(r *http.Request) (int, *Response, error) {
+	userID, err := caller(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var req SwapRequest
+	body, err := readRequestBody(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := decodeBody(body, &req); err != nil {
+		return 0, nil, err
+	}
+
+	swap, err := h.ss.Request(r.Context(), req.BookID, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, &Response{
+		Swap: swap,
+	}, nil
+}
+
+// ListSwaps is invoked by HTTP GET /swaps and lists the swaps of the caller.
+func (h *Handler) ListSwaps(r *http.Request) (int, *Response, error) {
+	userID, err := caller(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, &Response{
+		Swaps: h.ss.ListByUser(r.Context(), userID),
+	}, nil
+}
+
+// GetSwap is invoked by HTTP GET /swaps/{id} and answers to either party of the swap.
+func (h *Handler) GetSwap(r *http.Request) (int, *Response, error) {
+	userID, err := caller(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	swap, err := h.ss.Get(r.Context(), mux.Vars(r)["id"], userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, &Response{
+		Swap: swap,
+	}, nil
+}
+
+// swapAction returns the handler of POST /swaps/{id}/{action}, which moves
+// the swap on with the given SwapService method on behalf of the caller,
+// and of GET /swaps/{id}/tracking.
+func (h *Handler) swapAction(action func(ss *db.SwapService, ctx context.Context, swapID, userID string) (*db.Swap, error)) handlerFunc {
+	return func(r *http.Request) (int, *Response, error) {
+		swapID := mux.Vars(r)["id"]
+		userID, err := caller(r)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		swap, err := action(h.ss, r.Context(), swapID, userID)
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, &Response{
+			Swap: swap,
+		}, nil
+	}
+}
@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapEndpoints(t *testing.T) {
+	// Arrange
+	owner := db.User{ID: uuid.NewString(), Name: "Owner"}
+	requester := db.User{ID: uuid.NewString(), Name: "Requester"}
+	outsider := db.User{ID: uuid.NewString(), Name: "Outsider"}
+	book := db.Book{ID: uuid.NewString(), Name: "Swappable", OwnerID: owner.ID, Status: db.Available}
+	srv := newServer(t, []db.Book{book}, []db.User{owner, requester, outsider})
+
+	// Act
+	status, resp := post(t, srv.URL+"/swaps?user="+requester.ID, map[string]string{"book_id": book.ID})
+	require.Equal(t, http.StatusCreated, status)
+	require.NotNil(t, resp.Swap)
+	swapID := resp.Swap.ID
+
+	status, problem := sendProblem(t, http.MethodPost, fmt.Sprintf("%s/swaps/%s/accept?user=%s", srv.URL, swapID, requester.ID), nil)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "not_allowed", problem.Code)
+
+	status, _ = post(t, fmt.Sprintf("%s/swaps/%s/ship?user=%s", srv.URL, swapID, owner.ID), nil)
+	assert.Equal(t, http.StatusConflict, status)
+
+	for _, step := range []struct{ action, userID string }{
+		{"accept", owner.ID},
+		{"ship", owner.ID},
+		{"receive", requester.ID},
+	} {
+		status, _ = post(t, fmt.Sprintf("%s/swaps/%s/%s?user=%s", srv.URL, swapID, step.action, step.userID), nil)
+		require.Equal(t, http.StatusOK, status, step.action)
+	}
+
+	// Assert
+	status, resp = get(t, fmt.Sprintf("%s/swaps/%s?user=%s", srv.URL, swapID, owner.ID))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, db.SwapReceived, resp.Swap.Status)
+	status, problem = sendProblem(t, http.MethodGet, fmt.Sprintf("%s/swaps/%s?user=%s", srv.URL, swapID, outsider.ID), nil)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "not_allowed", problem.Code)
+	status, problem = sendProblem(t, http.MethodGet, srv.URL+"/swaps/"+swapID, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "authentication_required", problem.Code)
+
+	require.Eventually(t, func() bool {
+		status, resp = get(t, fmt.Sprintf("%s/swaps/%s/tracking?user=%s", srv.URL, swapID, requester.ID))
+		return status == http.StatusOK && resp.Swap.Order != nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, db.OrderCreated, resp.Swap.Order.Status)
+
+	status, resp = get(t, srv.URL+"/swaps?user="+owner.ID)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, resp.Swaps, 1)
+
+	status, resp = get(t, srv.URL+"/users/"+requester.ID)
+	assert.Equal(t, http.StatusOK, status)
+	require.Len(t, resp.Books, 1)
+	assert.Equal(t, db.Received, resp.Books[0].Status)
+
+	status, _ = get(t, srv.URL+"/swaps/not-found?user="+owner.ID)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = post(t, srv.URL+"/swaps?user=unknown", map[string]string{"book_id": book.ID})
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestSwapBookEndpoint(t *testing.T) {
+	// Arrange
+	owner := db.User{ID: uuid.NewString(), Name: "Owner"}
+	requester := db.User{ID: uuid.NewString(), Name: "Requester"}
+	book := db.Book{ID: uuid.NewString(), Name: "Wanted", OwnerID: owner.ID, Status: db.Available}
+	srv := newServer(t, []db.Book{book}, []db.User{owner, requester})
+
+	// Act
+	status, problem := sendProblem(t, http.MethodPost, fmt.Sprintf("%s/books/%s?user=%s", srv.URL, book.ID, owner.ID), nil)
+	require.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "not_allowed", problem.Code)
+	status, resp := post(t, fmt.Sprintf("%s/books/%s?user=%s", srv.URL, book.ID, requester.ID), nil)
+
+	// Assert
+	require.Equal(t, http.StatusCreated, status)
+	require.NotNil(t, resp.Swap)
+	assert.Equal(t, db.SwapRequested, resp.Swap.Status)
+	assert.Equal(t, requester.ID, resp.Swap.RequesterID)
+	status, resp = get(t, srv.URL+"/books/"+book.ID)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, db.Requested, resp.Books[0].Status)
+	assert.Equal(t, owner.ID, resp.Books[0].OwnerID)
+}
+
+func TestRelistEndpoint(t *testing.T) {
+	// Arrange
+	owner := db.User{ID: uuid.NewString(), Name: "Owner"}
+	book := db.Book{ID: uuid.NewString(), Name: "Swapped", OwnerID: owner.ID, Status: db.Swapped}
+	srv := newServer(t, []db.Book{book}, []db.User{owner})
+
+	// Act
+	status, resp := post(t, fmt.Sprintf("%s/books/%s/relist?user=%s", srv.URL, book.ID, owner.ID), nil)
+
+	// Assert
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, resp.Books, 1)
+	assert.Equal(t, db.Available, resp.Books[0].Status)
+	require.Len(t, resp.Books[0].History, 1)
+	assert.Equal(t, db.Swapped, resp.Books[0].History[0].From)
+
+	status, _ = post(t, fmt.Sprintf("%s/books/%s/relist?user=%s", srv.URL, book.ID, owner.ID), nil)
+	assert.Equal(t, http.StatusConflict, status)
+}
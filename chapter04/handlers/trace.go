@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// requestIDHeader is the header that carries the ID of a request.
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID matches the request IDs that are accepted from clients.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// statusRecorder remembers the status of a response and the error it reports.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	err    error
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// trace assigns every request an ID, which is returned in the X-Request-ID
+// header and carried by the request context into the services. A valid ID
+// sent by the client is kept, so that requests can be traced across services.
+// Once the request is served, its method, path, status and latency are logged
+// and counted in the metrics of the route that served it.
+func (h *Handler) trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, id)
+		ctx := db.WithRequestID(r.Context(), id)
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		latency := time.Since(start)
+		route := routeOf(r)
+		h.requests.Inc(r.Method, route, strconv.Itoa(rec.status))
+		h.latency.Observe(latency.Seconds(), r.Method, route)
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"latency", latency,
+		}
+		if rec.err != nil {
+			attrs = append(attrs, "error", rec.err.Error())
+		}
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		h.logger.Log(ctx, level, "request served", attrs...)
+	})
+}
+
+// routeOf returns the path template of the route that matched r, such as
+// /v1/books/{id}, so that requests for every book share their metrics.
+// Requests that match no route share the "unmatched" route.
+func routeOf(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if path, err := route.GetPathTemplate(); err == nil {
+			return path
+		}
+	}
+	return "unmatched"
+}
@@ -0,0 +1,152 @@
+package handlers_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logBuffer collects the JSON records logged by a server.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// records returns the records logged so far with the given message.
+func (b *logBuffer) records(t *testing.T, msg string) []map[string]any {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var records []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for scanner.Scan() {
+		var record map[string]any
+		if !assert.Nil(t, json.Unmarshal(scanner.Bytes(), &record)) {
+			continue
+		}
+		if record["msg"] == msg {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func TestRequestTracing(t *testing.T) {
+	// Arrange
+	user := db.User{ID: uuid.NewString(), Name: "Reader"}
+	book := db.Book{ID: uuid.NewString(), Name: "Traced", OwnerID: uuid.NewString(), Status: db.Available}
+	logs := &logBuffer{}
+	logger := slog.New(db.NewLogHandler(slog.NewJSONHandler(logs, nil)))
+	srv := newServerWithLogger(t, []db.Book{book}, []db.User{user}, logger)
+	token, err := testAuth.Token(user.ID)
+	require.Nil(t, err)
+
+	tests := map[string]struct {
+		requestID string
+		wantKept  bool
+	}{
+		"request ID of the client": {
+			requestID: "client-request-1",
+			wantKept:  true,
+		},
+		"no request ID": {},
+		"invalid request ID": {
+			requestID: "not a valid\tid",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/users/"+user.ID, nil)
+			require.Nil(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+			if tc.requestID != "" {
+				req.Header.Set("X-Request-ID", tc.requestID)
+			}
+
+			// Act
+			resp, err := http.DefaultClient.Do(req)
+			require.Nil(t, err)
+			resp.Body.Close()
+
+			// Assert
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			id := resp.Header.Get("X-Request-ID")
+			if tc.wantKept {
+				assert.Equal(t, tc.requestID, id)
+			} else {
+				assert.NoError(t, uuid.Validate(id))
+			}
+			var served map[string]any
+			require.Eventually(t, func() bool {
+				for _, record := range logs.records(t, "request served") {
+					if record["request_id"] == id {
+						served = record
+						return true
+					}
+				}
+				return false
+			}, time.Second, time.Millisecond)
+			assert.Equal(t, "INFO", served["level"])
+			assert.Equal(t, http.MethodGet, served["method"])
+			assert.Equal(t, "/v1/users/"+user.ID, served["path"])
+			assert.Equal(t, float64(http.StatusOK), served["status"])
+			assert.Contains(t, served, "latency")
+		})
+	}
+
+	// Act
+	status, _ := post(t, fmt.Sprintf("%s/books/%s?user=%s", srv.URL, book.ID, user.ID), nil)
+
+	// Assert
+	require.Equal(t, http.StatusCreated, status)
+	swapped := logs.records(t, "swap requested")
+	require.Len(t, swapped, 1)
+	assert.Equal(t, book.ID, swapped[0]["book_id"])
+	require.NotEmpty(t, swapped[0]["request_id"])
+	assert.Eventually(t, func() bool {
+		for _, record := range logs.records(t, "request served") {
+			if record["request_id"] == swapped[0]["request_id"] {
+				return record["path"] == "/books/"+book.ID
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+}
+
+func TestRequestTracingOfUnknownRoutes(t *testing.T) {
+	// Arrange
+	logs := &logBuffer{}
+	srv := newServerWithLogger(t, nil, nil, slog.New(db.NewLogHandler(slog.NewJSONHandler(logs, nil))))
+
+	// Act
+	resp, err := http.Get(srv.URL + "/not-found")
+	require.Nil(t, err)
+	resp.Body.Close()
+
+	// Assert
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.NotEmpty(t, resp.Header.Get("X-Request-ID"))
+	require.Eventually(t, func() bool {
+		return len(logs.records(t, "request served")) == 1
+	}, time.Second, time.Millisecond)
+	served := logs.records(t, "request served")[0]
+	assert.Equal(t, float64(http.StatusNotFound), served["status"])
+	assert.Contains(t, served["error"], "no such route /not-found")
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/gorilla/mux"
+)
+
+// editUser returns the handler of HTTP PUT and PATCH /users/{id}, which
+// users call with their own bearer token. PUT replaces the details of the
+// user with those in the body, while PATCH only changes the fields present
+// in the body.
+func (h *Handler) editUser(replace bool) handlerFunc {
+	return func(r *http.Request) (int, *Response, error) {
+		id := mux.Vars(r)["id"]
+		userID, err := caller(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		body, err := readRequestBody(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		var edit db.User
+		if err := decodeBody(body, &edit); err != nil {
+			return 0, nil, err
+		}
+
+		user, err := h.us.Update(r.Context(), id, userID, func(u *db.User) error {
+			if replace {
+				*u = edit
+			} else if err := json.Unmarshal(body, u); err != nil {
+				return err
+			}
+			return u.Validate()
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, &Response{
+			User: user,
+		}, nil
+	}
+}
+
+// DeleteUser is invoked by HTTP DELETE /users/{id} by the user themselves.
+func (h *Handler) DeleteUser(r *http.Request) (int, *Response, error) {
+	id := mux.Vars(r)["id"]
+	userID, err := caller(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := h.us.Delete(r.Context(), id, userID); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, &Response{
+		Message: "user deleted",
+	}, nil
+}
@@ -0,0 +1,102 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/handlers"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserCRUD(t *testing.T) {
+	// Arrange
+	other := db.User{ID: uuid.NewString(), Name: "Other"}
+	srv := newServer(t, nil, []db.User{other})
+	status, resp := post(t, srv.URL+"/users", handlers.UserRequest{
+		User:     db.User{Name: "Reader", Address: "Partizanska 1", PostCode: "1000", Country: "MK"},
+		Password: "correct horse",
+	})
+	require.Equal(t, http.StatusOK, status)
+	user := *resp.User
+	url := fmt.Sprintf("%s/users/%s?user=", srv.URL, user.ID)
+
+	// Act
+	status, resp = post(t, srv.URL+"/users?user="+user.ID, db.User{ID: user.ID, Name: "Renamed", Address: "Partizanska 1", PostCode: "1000", Country: "MK"})
+
+	// Assert
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, user.ID, resp.User.ID)
+
+	// Act
+	status, resp = send(t, http.MethodPatch, url+user.ID, map[string]string{"country": "DE", "post_code": "10115"})
+
+	// Assert
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Renamed", resp.User.Name)
+	assert.Equal(t, "DE", resp.User.Country)
+
+	// Act
+	status, resp = send(t, http.MethodPut, url+user.ID, db.User{Name: "Replaced", Address: "Main Street 1", PostCode: "10001", Country: "US"})
+
+	// Assert
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, user.ID, resp.User.ID)
+	assert.Equal(t, "Replaced", resp.User.Name)
+	assert.Equal(t, "US", resp.User.Country)
+
+	// Act
+	status, _ = send(t, http.MethodPatch, url+other.ID, map[string]string{"name": "Hijacked"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = post(t, srv.URL+"/books?user="+user.ID, db.Book{Name: "Owned", Author: "Author", OwnerID: user.ID})
+	require.Equal(t, http.StatusOK, status)
+	status, _ = send(t, http.MethodDelete, url+user.ID, nil)
+	assert.Equal(t, http.StatusConflict, status)
+}
+
+func TestDeleteUser(t *testing.T) {
+	// Arrange
+	user := db.User{ID: uuid.NewString(), Name: "Leaving"}
+	srv := newServer(t, nil, []db.User{user})
+	url := fmt.Sprintf("%s/users/%s?user=%s", srv.URL, user.ID, user.ID)
+
+	// Act
+	status, _ := send(t, http.MethodDelete, url, nil)
+
+	// Assert
+	require.Equal(t, http.StatusOK, status)
+	status, _ = get(t, srv.URL+"/users/"+user.ID)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = send(t, http.MethodDelete, url, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = post(t, srv.URL+"/books?user="+user.ID, db.Book{Name: "Orphan", Author: "Author"})
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestUserValidation(t *testing.T) {
+	// Arrange
+	user := db.User{ID: uuid.NewString(), Name: "Reader", Address: "Partizanska 1", PostCode: "1000", Country: "MK"}
+	srv := newServer(t, nil, []db.User{user})
+
+	// Act
+	status, problem := sendProblem(t, http.MethodPost, srv.URL+"/users", handlers.UserRequest{
+		User:     db.User{Name: "Reader", Address: "Partizanska 1", PostCode: "10", Country: "MK"},
+		Password: "correct horse",
+	})
+
+	// Assert
+	require.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, []db.FieldError{{Field: "post_code", Message: "is not a valid post code for MK"}}, problem.Errors)
+
+	// Act
+	status, problem = sendProblem(t, http.MethodPatch, fmt.Sprintf("%s/users/%s?user=%s", srv.URL, user.ID, user.ID), map[string]string{"name": ""})
+
+	// Assert
+	require.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, []db.FieldError{{Field: "name", Message: "is required"}}, problem.Errors)
+	_, resp := get(t, srv.URL+"/users/"+user.ID)
+	assert.Equal(t, "Reader", resp.User.Name)
+}
@@ -0,0 +1,244 @@
+// Package metrics collects counters, histograms and gauges and exposes them
+// in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds of latency histograms, in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds metrics by name and is safe for concurrent use.
+// Asking it for a metric that is already registered returns the existing metric,
+// so that services sharing a registry can share metrics.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// family is a metric and its series, one for every combination of label values.
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	// buckets are the upper bounds of the buckets of a histogram.
+	buckets []float64
+	// gauge reads the values of a gauge by the value of its only label.
+	gauge func() map[string]float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// counts are the number of observations in each bucket of a histogram.
+	counts []uint64
+	count  uint64
+}
+
+// Counter is a metric that only goes up, such as the number of requests.
+type Counter struct {
+	f *family
+}
+
+// Histogram counts observations, such as latencies, in buckets.
+type Histogram struct {
+	f *family
+}
+
+// Counter returns the counter with the given name, registering it if needed.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(&family{name: name, help: help, kind: "counter", labels: labels})}
+}
+
+// Histogram returns the histogram with the given name and bucket upper bounds,
+// registering it if needed.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{r.register(&family{name: name, help: help, kind: "histogram", labels: labels, buckets: buckets})}
+}
+
+// GaugeFunc registers a gauge with a single label, whose values are read from f
+// every time the registry is written.
+func (r *Registry) GaugeFunc(name, help, label string, f func() map[string]float64) {
+	r.register(&family{name: name, help: help, kind: "gauge", labels: []string{label}, gauge: f})
+}
+
+func (r *Registry) register(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.families[f.name]; ok {
+		if existing.kind != f.kind || strings.Join(existing.labels, ",") != strings.Join(f.labels, ",") {
+			panic(fmt.Sprintf("metrics: %s is already registered as a different %s", f.name, existing.kind))
+		}
+		return existing
+	}
+	f.series = make(map[string]*series)
+	r.families[f.name] = f
+	return f
+}
+
+// Inc adds one to the series with the given label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the series with the given label values.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	c.f.get(labelValues).value += v
+}
+
+// Value returns the value of the series with the given label values.
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	return c.f.get(labelValues).value
+}
+
+// Observe records v in the series with the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.get(labelValues)
+	for i, upper := range h.f.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.value += v
+}
+
+// get returns the series with the given label values, creating it if needed.
+// The lock of the family must be held.
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s has labels %v, got values %v", f.name, f.labels, labelValues))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: labelValues, counts: make([]uint64, len(f.buckets))}
+		f.series[key] = s
+	}
+	return s
+}
+
+// WriteTo writes every metric in the text exposition format, ordered by name
+// and then by label values.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	var b strings.Builder
+	for _, f := range families {
+		f.write(&b)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics of the registry to a scraper.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	r.WriteTo(w)
+}
+
+func (f *family) write(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n", f.name, escape(f.help, false))
+	fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.kind)
+
+	if f.gauge != nil {
+		values := f.gauge()
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(b, "%s%s %s\n", f.name, labelPairs(f.labels, []string{k}), formatFloat(values[k]))
+		}
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := f.series[k]
+		if f.kind != "histogram" {
+			fmt.Fprintf(b, "%s%s %s\n", f.name, labelPairs(f.labels, s.labelValues), formatFloat(s.value))
+			continue
+		}
+		labels := append(f.labels[:len(f.labels):len(f.labels)], "le")
+		for i, upper := range f.buckets {
+			values := append(s.labelValues[:len(s.labelValues):len(s.labelValues)], formatFloat(upper))
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, labelPairs(labels, values), s.counts[i])
+		}
+		values := append(s.labelValues[:len(s.labelValues):len(s.labelValues)], "+Inf")
+		fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, labelPairs(labels, values), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", f.name, labelPairs(f.labels, s.labelValues), formatFloat(s.value))
+		fmt.Fprintf(b, "%s_count%s %d\n", f.name, labelPairs(f.labels, s.labelValues), s.count)
+	}
+}
+
+// labelPairs formats labels and their values as {name="value",...}.
+func labelPairs(labels, values []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(labels))
+	for i, l := range labels {
+		pairs[i] = l + `="` + escape(values[i], true) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escape escapes backslashes and line feeds, and the double quotes of label values.
+func escape(s string, quotes bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quotes {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,103 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/angel-gruevski/test-driven-development-in-go/chapter04/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTo(t *testing.T) {
+	// Arrange
+	reg := metrics.NewRegistry()
+	requests := reg.Counter("requests_total", "Requests served.", "method", "path")
+	requests.Inc("GET", "/books")
+	requests.Add(2, "POST", `/books/"quoted"`)
+	requests.Inc("GET", "/books")
+	latency := reg.Histogram("latency_seconds", "Latency of requests.", []float64{0.1, 1}, "method")
+	latency.Observe(0.05, "GET")
+	latency.Observe(0.5, "GET")
+	latency.Observe(5, "GET")
+	reg.GaugeFunc("books", "Books by status.", "status", func() map[string]float64 {
+		return map[string]float64{"SWAPPED": 1, "AVAILABLE": 3}
+	})
+	reg.Counter("idle_total", "Counter without series.\nSecond line.")
+
+	// Act
+	var b strings.Builder
+	_, err := reg.WriteTo(&b)
+
+	// Assert
+	require.Nil(t, err)
+	assert.Equal(t, `# HELP books Books by status.
+# TYPE books gauge
+books{status="AVAILABLE"} 3
+books{status="SWAPPED"} 1
+# HELP idle_total Counter without series.\nSecond line.
+# TYPE idle_total counter
+# HELP latency_seconds Latency of requests.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{method="GET",le="0.1"} 1
+latency_seconds_bucket{method="GET",le="1"} 2
+latency_seconds_bucket{method="GET",le="+Inf"} 3
+latency_seconds_sum{method="GET"} 5.55
+latency_seconds_count{method="GET"} 3
+# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{method="GET",path="/books"} 2
+requests_total{method="POST",path="/books/\"quoted\""} 2
+`, b.String())
+}
+
+func TestRegister(t *testing.T) {
+	t.Run("same metric", func(t *testing.T) {
+		// Arrange
+		reg := metrics.NewRegistry()
+		reg.Counter("swaps_total", "Swaps.", "action").Inc("request")
+
+		// Act
+		c := reg.Counter("swaps_total", "Swaps.", "action")
+
+		// Assert
+		assert.Equal(t, float64(1), c.Value("request"))
+	})
+
+	t.Run("different metric with the same name", func(t *testing.T) {
+		// Arrange
+		reg := metrics.NewRegistry()
+		reg.Counter("swaps_total", "Swaps.", "action")
+
+		// Assert
+		assert.Panics(t, func() { reg.Histogram("swaps_total", "Swaps.", metrics.DefaultBuckets, "action") })
+		assert.Panics(t, func() { reg.Counter("swaps_total", "Swaps.", "action", "user") })
+	})
+
+	t.Run("wrong number of label values", func(t *testing.T) {
+		// Arrange
+		reg := metrics.NewRegistry()
+		c := reg.Counter("swaps_total", "Swaps.", "action")
+
+		// Assert
+		assert.Panics(t, func() { c.Inc() })
+		assert.Panics(t, func() { c.Add(-1, "request") })
+	})
+}
+
+func TestServeHTTP(t *testing.T) {
+	// Arrange
+	reg := metrics.NewRegistry()
+	reg.Counter("requests_total", "Requests served.").Inc()
+	rec := httptest.NewRecorder()
+
+	// Act
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, metrics.ContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "requests_total 1\n")
+}
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	db "github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,17 +14,17 @@ type BookOperationsService struct {
 	mock.Mock
 }
 
-// ListByUser provides a mock function with given fields: userID
-func (_m *BookOperationsService) ListByUser(userID string) []db.Book {
-	ret := _m.Called(userID)
+// ListByUser provides a mock function with given fields: ctx, userID
+func (_m *BookOperationsService) ListByUser(ctx context.Context, userID string) []db.Book {
+	ret := _m.Called(ctx, userID)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ListByUser")
 	}
 
 	var r0 []db.Book
-	if rf, ok := ret.Get(0).(func(string) []db.Book); ok {
-		r0 = rf(userID)
+	if rf, ok := ret.Get(0).(func(context.Context, string) []db.Book); ok {
+		r0 = rf(ctx, userID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]db.Book)
@@ -2,13 +2,78 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	db "github.com/angel-gruevski/test-driven-development-in-go/chapter04/db"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // PostingService is an autogenerated mock type for the PostingService type
 type PostingService struct {
 	mock.Mock
 }
 
+// NewOrder provides a mock function with given fields: ctx, b
+func (_m *PostingService) NewOrder(ctx context.Context, b db.Book) (*db.Order, error) {
+	ret := _m.Called(ctx, b)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NewOrder")
+	}
+
+	var r0 *db.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, db.Book) (*db.Order, error)); ok {
+		return rf(ctx, b)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, db.Book) *db.Order); ok {
+		r0 = rf(ctx, b)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*db.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, db.Book) error); ok {
+		r1 = rf(ctx, b)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Track provides a mock function with given fields: ctx, orderID
+func (_m *PostingService) Track(ctx context.Context, orderID string) (*db.Order, error) {
+	ret := _m.Called(ctx, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Track")
+	}
+
+	var r0 *db.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*db.Order, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *db.Order); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*db.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewPostingService creates a new instance of PostingService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewPostingService(t interface {